@@ -0,0 +1,107 @@
+// Package ansi at the root of the module is a deprecated alias for the
+// github.com/adnsv/go-utils/ansi package. All declarations here forward to
+// that package, so that values are interchangeable between the two import
+// paths.
+//
+// Deprecated: import github.com/adnsv/go-utils/ansi instead.
+package ansi
+
+import (
+	"os"
+
+	"github.com/adnsv/go-utils/ansi"
+)
+
+// Deprecated: use ansi.ColorIndex from github.com/adnsv/go-utils/ansi.
+type ColorIndex = ansi.ColorIndex
+
+// Deprecated: use ansi.Style from github.com/adnsv/go-utils/ansi.
+type Style = ansi.Style
+
+// Deprecated: use ansi.OutputState from github.com/adnsv/go-utils/ansi.
+type OutputState = ansi.OutputState
+
+const Black = ansi.Black
+const White = ansi.White
+
+const (
+	Reset         = ansi.Reset
+	Bold          = ansi.Bold
+	Dim           = ansi.Dim
+	Italic        = ansi.Italic
+	Underline     = ansi.Underline
+	Blinking      = ansi.Blinking
+	Inverse       = ansi.Inverse
+	Hidden        = ansi.Hidden
+	StrikeThrough = ansi.StrikeThrough
+)
+
+// Deprecated: use ansi.RGB from github.com/adnsv/go-utils/ansi.
+func RGB(r, g, b byte) ColorIndex {
+	return ansi.RGB(r, g, b)
+}
+
+// Deprecated: use ansi.Gray from github.com/adnsv/go-utils/ansi.
+func Gray(l byte) ColorIndex {
+	return ansi.Gray(l)
+}
+
+// Deprecated: use ansi.Foreground from github.com/adnsv/go-utils/ansi.
+func Foreground(v ColorIndex) string {
+	return ansi.Foreground(v)
+}
+
+// Deprecated: use ansi.Background from github.com/adnsv/go-utils/ansi.
+func Background(v ColorIndex) string {
+	return ansi.Background(v)
+}
+
+// Deprecated: use ansi.ForegroundBlack from github.com/adnsv/go-utils/ansi.
+func ForegroundBlack() string {
+	return ansi.ForegroundBlack()
+}
+
+// Deprecated: use ansi.BackgroundBlack from github.com/adnsv/go-utils/ansi.
+func BackgroundBlack() string {
+	return ansi.BackgroundBlack()
+}
+
+// Deprecated: use ansi.ForegroundWhite from github.com/adnsv/go-utils/ansi.
+func ForegroundWhite() string {
+	return ansi.ForegroundWhite()
+}
+
+// Deprecated: use ansi.BackgroundWhite from github.com/adnsv/go-utils/ansi.
+func BackgroundWhite() string {
+	return ansi.BackgroundWhite()
+}
+
+// Deprecated: use ansi.ForegroundGray from github.com/adnsv/go-utils/ansi.
+func ForegroundGray(l byte) string {
+	return ansi.ForegroundGray(l)
+}
+
+// Deprecated: use ansi.BackgroundGray from github.com/adnsv/go-utils/ansi.
+func BackgroundGray(l byte) string {
+	return ansi.BackgroundGray(l)
+}
+
+// Deprecated: use ansi.ForegroundRGB from github.com/adnsv/go-utils/ansi.
+func ForegroundRGB(r, g, b byte) string {
+	return ansi.ForegroundRGB(r, g, b)
+}
+
+// Deprecated: use ansi.BackgroundRGB from github.com/adnsv/go-utils/ansi.
+func BackgroundRGB(r, g, b byte) string {
+	return ansi.BackgroundRGB(r, g, b)
+}
+
+// Deprecated: use ansi.SetupOutput from github.com/adnsv/go-utils/ansi.
+func SetupOutput(output *os.File) *OutputState {
+	return ansi.SetupOutput(output)
+}
+
+// Deprecated: use ansi.SetupStdout from github.com/adnsv/go-utils/ansi.
+func SetupStdout() *OutputState {
+	return ansi.SetupStdout()
+}
@@ -1,26 +1,35 @@
 package ansi
 
+import "strconv"
+
 // An index into a standard 256-color ANSI palette
 type ColorIndex uint8
 
 const Black = ColorIndex(0)
 const White = ColorIndex(15)
 
-// RGB maps rgb color to an index in standard ANSI 256-color palette
-//
-// input color component values [0..ff] are snapped to
-// - 0x00
-// - 0x33
-// - 0x66
-// - 0x99
-// - 0xcc
-// - 0xff
-//
+// Base color indices, actual colors depend on the terminal's configuration
+const (
+	Red = ColorIndex(iota + 1)
+	Green
+	Yellow
+	Blue
+	Magenta
+	Cyan
+	LightGray
+	DarkGray
+	BrightRed
+	BrightGreen
+	BrightYellow
+	BrightBlue
+	BrightMagenta
+	BrightCyan
+)
+
+// RGB maps rgb color to the perceptually closest index in standard ANSI
+// 256-color palette (see Nearest)
 func RGB(r, g, b byte) ColorIndex {
-	rr := (uint(r) * 3) >> 7
-	gg := (uint(g) * 3) >> 7
-	bb := (uint(b) * 3) >> 7
-	return ColorIndex(rr*36 + gg*6 + bb + 16)
+	return Nearest(RGBColor{r, g, b})
 }
 
 // Gray maps luminance to an index in standard ANSI 256-color palette
@@ -63,3 +72,235 @@ func Gray(l byte) ColorIndex {
 	v += 231
 	return ColorIndex(v)
 }
+
+// ColorDepth specifies the number of colors a terminal is able to display
+type ColorDepth int
+
+const (
+	DepthNone      = ColorDepth(iota) // no color support
+	Depth16                           // 16 base colors (SGR 30..37, 90..97)
+	Depth256                          // standard 256-color palette (SGR 38;5;n)
+	DepthTrueColor                    // 24-bit colors (SGR 38;2;r;g;b)
+)
+
+func (d ColorDepth) String() string {
+	switch d {
+	case DepthNone:
+		return "none"
+	case Depth16:
+		return "16"
+	case Depth256:
+		return "256"
+	case DepthTrueColor:
+		return "truecolor"
+	default:
+		return "unknown"
+	}
+}
+
+// RGBColor is a 24-bit color value
+type RGBColor struct {
+	R, G, B byte
+}
+
+// Index maps the color to the perceptually closest entry in standard ANSI
+// 256-color palette (see Nearest)
+func (c RGBColor) Index() ColorIndex {
+	return Nearest(c)
+}
+
+// Base maps the color to the perceptually closest of the 16 base colors (see
+// NearestBase)
+func (c RGBColor) Base() ColorIndex {
+	return NearestBase(c)
+}
+
+// Foreground produces the best foreground color sequence for the specified
+// color depth, returns an empty string for DepthNone
+func (c RGBColor) Foreground(depth ColorDepth) string {
+	switch depth {
+	case DepthTrueColor:
+		return ForegroundTrueColor(c.R, c.G, c.B)
+	case Depth256:
+		return Foreground(c.Index())
+	case Depth16:
+		return ForegroundBase(c.Base())
+	default:
+		return ""
+	}
+}
+
+// Background produces the best background color sequence for the specified
+// color depth, returns an empty string for DepthNone
+func (c RGBColor) Background(depth ColorDepth) string {
+	switch depth {
+	case DepthTrueColor:
+		return BackgroundTrueColor(c.R, c.G, c.B)
+	case Depth256:
+		return Background(c.Index())
+	case Depth16:
+		return BackgroundBase(c.Base())
+	default:
+		return ""
+	}
+}
+
+// basePalette contains xterm default values for the 16 base colors
+var basePalette = [16]RGBColor{
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00}, {0xcd, 0xcd, 0x00},
+	{0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd}, {0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5},
+	{0x7f, 0x7f, 0x7f}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+// cubeLevels contains xterm channel values for the 6x6x6 color cube
+var cubeLevels = [6]byte{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+
+// RGB returns the color value that a typical (xterm-compatible) terminal uses
+// for the palette entry
+//
+//   - 0..15: base colors
+//   - 16..231: 6x6x6 color cube
+//   - 232..255: grayscale ramp
+func (v ColorIndex) RGB() RGBColor {
+	switch {
+	case v < 16:
+		return basePalette[v]
+	case v < 232:
+		i := int(v) - 16
+		return RGBColor{cubeLevels[i/36], cubeLevels[(i/6)%6], cubeLevels[i%6]}
+	default:
+		l := byte(8 + 10*(int(v)-232))
+		return RGBColor{l, l, l}
+	}
+}
+
+// Base maps the palette entry to one of the 16 base colors
+func (v ColorIndex) Base() ColorIndex {
+	if v < 16 {
+		return v
+	}
+	return v.RGB().Base()
+}
+
+type colorKind uint8
+
+const (
+	colorDefault = colorKind(iota)
+	colorIndexed
+	colorRGB
+)
+
+// Color is a color value used in styles, which is either an entry in the
+// standard 256-color palette or a 24-bit color.
+//
+// The zero value represents the terminal's default color.
+type Color struct {
+	kind  colorKind
+	index ColorIndex
+	rgb   RGBColor
+}
+
+// DefaultColor is the terminal's default color
+var DefaultColor = Color{}
+
+// Indexed makes a color that refers to an entry in the 256-color palette
+func Indexed(v ColorIndex) Color {
+	return Color{kind: colorIndexed, index: v}
+}
+
+// RGB24 makes a 24-bit color
+func RGB24(r, g, b byte) Color {
+	return Color{kind: colorRGB, rgb: RGBColor{r, g, b}}
+}
+
+// IsDefault returns true if c represents the terminal's default color
+func (c Color) IsDefault() bool {
+	return c.kind == colorDefault
+}
+
+// Index returns the palette index of an indexed color
+func (c Color) Index() (v ColorIndex, ok bool) {
+	return c.index, c.kind == colorIndexed
+}
+
+// RGB returns the 24-bit color value, indexed colors are converted with
+// ColorIndex.RGB, ok is false for the default color.
+func (c Color) RGB() (v RGBColor, ok bool) {
+	switch c.kind {
+	case colorRGB:
+		return c.rgb, true
+	case colorIndexed:
+		return c.index.RGB(), true
+	default:
+		return RGBColor{}, false
+	}
+}
+
+// Downgrade converts the color to the best representation available with the
+// specified color depth:
+//
+//   - DepthTrueColor: unchanged
+//   - Depth256: 24-bit colors are mapped into the palette
+//   - Depth16: all colors are mapped to the 16 base colors
+//   - DepthNone: all colors become default
+func (c Color) Downgrade(depth ColorDepth) Color {
+	switch {
+	case c.kind == colorDefault || depth == DepthTrueColor:
+		return c
+	case depth == Depth256:
+		if c.kind == colorRGB {
+			return Indexed(c.rgb.Index())
+		}
+		return c
+	case depth == Depth16:
+		if c.kind == colorRGB {
+			return Indexed(c.rgb.Base())
+		}
+		return Indexed(c.index.Base())
+	default:
+		return DefaultColor
+	}
+}
+
+// sgr returns SGR parameters for the color, code is one of 38 (foreground),
+// 48 (background), or 58 (underline); base colors are encoded with their
+// short codes (30..37, 90..97 and 40..47, 100..107)
+func (c Color) sgr(code int) string {
+	switch c.kind {
+	case colorIndexed:
+		if c.index < 16 && code == 38 {
+			return baseParam(30, 90, c.index)
+		} else if c.index < 16 && code == 48 {
+			return baseParam(40, 100, c.index)
+		}
+		return strconv.Itoa(code) + ";5;" + strconv.Itoa(int(c.index))
+	case colorRGB:
+		return strconv.Itoa(code) + ";2;" + strconv.Itoa(int(c.rgb.R)) + ";" +
+			strconv.Itoa(int(c.rgb.G)) + ";" + strconv.Itoa(int(c.rgb.B))
+	default:
+		return strconv.Itoa(code + 1)
+	}
+}
+
+// Palette maps the 256 palette indices to colors
+type Palette [256]RGBColor
+
+// StandardPalette returns default xterm colors for all palette entries (see
+// ColorIndex.RGB)
+func StandardPalette() Palette {
+	var p Palette
+	for i := range p {
+		p[i] = ColorIndex(i).RGB()
+	}
+	return p
+}
+
+// Hex formats the color as #rrggbb
+func (c RGBColor) Hex() string {
+	const digits = "0123456789abcdef"
+	return string([]byte{'#',
+		digits[c.R>>4], digits[c.R&15],
+		digits[c.G>>4], digits[c.G&15],
+		digits[c.B>>4], digits[c.B&15]})
+}
@@ -0,0 +1,101 @@
+package ansi
+
+import "testing"
+
+func TestRGBColorForeground(t *testing.T) {
+	c := RGBColor{0xff, 0x80, 0x00}
+	tests := []struct {
+		depth ColorDepth
+		want  string
+	}{
+		{DepthNone, ""},
+		{Depth16, "\x1b[31m"},
+		{Depth256, "\x1b[38;5;208m"},
+		{DepthTrueColor, "\x1b[38;2;255;128;0m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.depth.String(), func(t *testing.T) {
+			if got := c.Foreground(tt.depth); got != tt.want {
+				t.Errorf("Foreground(%v) = %q, want %q", tt.depth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorIndexBase(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		if got := ColorIndex(i).Base(); got != ColorIndex(i) {
+			t.Errorf("ColorIndex(%d).Base() = %d", i, got)
+		}
+		if got := ColorIndex(i).RGB().Base(); got != ColorIndex(i) {
+			t.Errorf("ColorIndex(%d).RGB().Base() = %d", i, got)
+		}
+	}
+	if got := ColorIndex(196).Base(); got != 9 {
+		t.Errorf("ColorIndex(196).Base() = %d, want 9", got)
+	}
+	if got := ColorIndex(232).Base(); got != Black {
+		t.Errorf("ColorIndex(232).Base() = %d, want 0", got)
+	}
+}
+
+func TestNearest(t *testing.T) {
+	tests := []struct {
+		c    RGBColor
+		want ColorIndex
+	}{
+		{RGBColor{0, 0, 0}, 16},
+		{RGBColor{0xff, 0xff, 0xff}, 231},
+		{RGBColor{0x80, 0x80, 0x80}, 244}, // gray ramp is closer than cube's 0x87
+		{RGBColor{0xff, 0x00, 0x00}, 196},
+		{RGBColor{0x5f, 0x87, 0xaf}, 67},
+		{RGBColor{0x7f, 0x7f, 0x7f}, 8}, // base colors are included
+		{RGBColor{0x5c, 0x5c, 0xff}, 12},
+	}
+	for _, tt := range tests {
+		if got := Nearest(tt.c); got != tt.want {
+			t.Errorf("Nearest(%v) = %d, want %d", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestRGBNearest(t *testing.T) {
+	// the cube snap used to map a dark gray into the cube at 0x5f
+	if got := RGB(0x30, 0x30, 0x30); got != 236 {
+		t.Errorf("RGB(0x30, 0x30, 0x30) = %d, want 236", got)
+	}
+	c := RGB24(0x30, 0x30, 0x30)
+	if got, _ := c.Downgrade(Depth256).Index(); got != 236 {
+		t.Errorf("Downgrade(Depth256) = %d, want 236", got)
+	}
+	if got := ForegroundRGB(0x30, 0x30, 0x30); got != "\x1b[38;5;236m" {
+		t.Errorf("ForegroundRGB() = %q", got)
+	}
+}
+
+func TestContrastRatio(t *testing.T) {
+	black, white := RGBColor{0, 0, 0}, RGBColor{0xff, 0xff, 0xff}
+	if r := ContrastRatio(black, white); r < 20.99 || r > 21.01 {
+		t.Errorf("ContrastRatio(black, white) = %v, want 21", r)
+	}
+	if r := ContrastRatio(white, white); r != 1 {
+		t.Errorf("ContrastRatio(white, white) = %v, want 1", r)
+	}
+	if got := ReadableForeground(RGBColor{0xff, 0xd7, 0x00}); got != black {
+		t.Errorf("ReadableForeground(yellow) = %v, want black", got)
+	}
+	if got := ReadableForeground(RGBColor{0x00, 0x00, 0x87}); got != white {
+		t.Errorf("ReadableForeground(navy) = %v, want white", got)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	stops := []RGBColor{{0, 0, 0}, {0xff, 0xff, 0xff}}
+	g := Gradient(stops, 3)
+	if g[0] != stops[0] || g[2] != stops[1] {
+		t.Errorf("Gradient() ends = %v, %v", g[0], g[2])
+	}
+	if g[1] != (RGBColor{0xbc, 0xbc, 0xbc}) { // 50% in linear light
+		t.Errorf("Gradient() middle = %v", g[1])
+	}
+}
@@ -0,0 +1,162 @@
+package ansi
+
+import (
+	"math"
+	"sync"
+)
+
+// linear converts an sRGB channel value to linear light
+func linear(v byte) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// encode converts a linear light value to an sRGB channel value
+func encode(c float64) byte {
+	if c <= 0.0031308 {
+		c *= 12.92
+	} else {
+		c = 1.055*math.Pow(c, 1/2.4) - 0.055
+	}
+	return byte(math.Round(math.Max(0, math.Min(1, c)) * 255))
+}
+
+type lab struct {
+	l, a, b float64
+}
+
+// toLab converts the color to CIELAB (D65 white point)
+func (c RGBColor) toLab() lab {
+	r, g, b := linear(c.R), linear(c.G), linear(c.B)
+	x := (0.4124*r + 0.3576*g + 0.1805*b) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*b
+	z := (0.0193*r + 0.1192*g + 0.9505*b) / 1.08883
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+func (p lab) dist(q lab) float64 {
+	dl, da, db := p.l-q.l, p.a-q.a, p.b-q.b
+	return dl*dl + da*da + db*db
+}
+
+var (
+	paletteLabOnce sync.Once
+	paletteLab     [256]lab
+)
+
+func nearestInRange(c RGBColor, best ColorIndex, bestDist float64, first, last int) (ColorIndex, float64) {
+	paletteLabOnce.Do(func() {
+		for i := range paletteLab {
+			paletteLab[i] = ColorIndex(i).RGB().toLab()
+		}
+	})
+	v := c.toLab()
+	for i := first; i <= last; i++ {
+		if d := v.dist(paletteLab[i]); d < bestDist {
+			best, bestDist = ColorIndex(i), d
+		}
+	}
+	return best, bestDist
+}
+
+// Nearest finds the perceptually closest entry in the full 256-color palette,
+// choosing between the color cube and the grayscale ramp. Base colors assume
+// xterm default values, which terminals often change: on a tie, the entries of
+// the cube and the ramp are preferred.
+func Nearest(c RGBColor) ColorIndex {
+	best, d := nearestInRange(c, 0, math.Inf(1), 16, 255)
+	best, _ = nearestInRange(c, best, d, 0, 15)
+	return best
+}
+
+// NearestBase finds the perceptually closest of the 16 base colors, assuming
+// xterm default values for them
+func NearestBase(c RGBColor) ColorIndex {
+	best, _ := nearestInRange(c, 0, math.Inf(1), 0, 15)
+	return best
+}
+
+// Blend mixes two colors in linear light, t=0 produces a, t=1 produces b
+func Blend(a, b RGBColor, t float64) RGBColor {
+	t = math.Max(0, math.Min(1, t))
+	mix := func(x, y byte) byte {
+		lx, ly := linear(x), linear(y)
+		return encode(lx + (ly-lx)*t)
+	}
+	return RGBColor{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B)}
+}
+
+// GradientAt returns a color at position t (0..1) on a gradient with evenly
+// spaced stops, e.g. for rendering heat-maps
+func GradientAt(stops []RGBColor, t float64) RGBColor {
+	switch len(stops) {
+	case 0:
+		return RGBColor{}
+	case 1:
+		return stops[0]
+	}
+	t = math.Max(0, math.Min(1, t))
+	pos := t * float64(len(stops)-1)
+	i := int(pos)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	return Blend(stops[i], stops[i+1], pos-float64(i))
+}
+
+// Gradient produces n colors evenly distributed on a gradient with evenly
+// spaced stops
+func Gradient(stops []RGBColor, n int) []RGBColor {
+	ret := make([]RGBColor, n)
+	for i := range ret {
+		t := 0.0
+		if n > 1 {
+			t = float64(i) / float64(n-1)
+		}
+		ret[i] = GradientAt(stops, t)
+	}
+	return ret
+}
+
+// RelativeLuminance computes the relative luminance of a color as defined by
+// WCAG 2: 0 for black, 1 for white
+func RelativeLuminance(c RGBColor) float64 {
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
+
+// ContrastRatio computes the WCAG 2 contrast ratio between two colors, ranges
+// from 1 (no contrast) to 21 (black on white). WCAG AA requires at least 4.5
+// for normal text.
+func ContrastRatio(a, b RGBColor) float64 {
+	la, lb := RelativeLuminance(a), RelativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// ReadableForeground picks the candidate with the highest contrast against
+// the background, chooses between black and white if no candidates are
+// provided
+func ReadableForeground(bg RGBColor, candidates ...RGBColor) RGBColor {
+	if len(candidates) == 0 {
+		candidates = []RGBColor{{0, 0, 0}, {0xff, 0xff, 0xff}}
+	}
+	best, bestRatio := candidates[0], 0.0
+	for _, c := range candidates {
+		if r := ContrastRatio(c, bg); r > bestRatio {
+			best, bestRatio = c, r
+		}
+	}
+	return best
+}
@@ -0,0 +1,298 @@
+package ansi
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// segment is a piece of text with uniform style
+type segment struct {
+	text  string
+	style Style
+	link  string
+}
+
+// segments splits styled terminal output into text segments, dropping all
+// escape sequences and control characters.
+//
+// A carriage return that is not followed by a line feed erases the current
+// line, this approximates the way progress indicators are displayed on
+// terminals.
+func segments(s string) []segment {
+	var segs []segment
+	lineSeg, lineOff := 0, 0 // start of the current line
+	cr := false
+	for _, t := range Tokenize(s) {
+		switch t.Kind {
+		case TokenControl:
+			if t.Raw == "\r" {
+				cr = true
+			}
+			continue
+		case TokenText:
+		default:
+			continue
+		}
+		text := t.Raw
+		if cr {
+			cr = false
+			if text[0] != '\n' && lineSeg < len(segs) {
+				segs[lineSeg].text = segs[lineSeg].text[:lineOff]
+				segs = segs[:lineSeg+1]
+			}
+		}
+		if n := len(segs); n > 0 && segs[n-1].style == t.Style && segs[n-1].link == t.Link {
+			segs[n-1].text += text
+		} else {
+			segs = append(segs, segment{text: text, style: t.Style, link: t.Link})
+		}
+		if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+			lineSeg = len(segs) - 1
+			lineOff = len(segs[lineSeg].text) - len(text) + i + 1
+		}
+	}
+	return segs
+}
+
+// HTMLOptions configures ToHTML
+type HTMLOptions struct {
+	// Classes enables CSS classes for attributes and indexed colors instead
+	// of inline styles, see HTMLStyleSheet. 24-bit colors are always inlined.
+	Classes bool
+
+	// ClassPrefix is prepended to class names, defaults to "ansi-"
+	ClassPrefix string
+
+	// Palette provides colors for indexed colors in inline styles, defaults
+	// to StandardPalette
+	Palette *Palette
+
+	// FileLinks allows file: hyperlinks, by default only http and https
+	// hyperlinks are converted, others are rendered as plain text
+	FileLinks bool
+}
+
+// allowLink checks the hyperlink scheme, so that captured output can not
+// inject javascript: or data: URLs into the page
+func (o *HTMLOptions) allowLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return true
+	case "file":
+		return o.FileLinks
+	}
+	return false
+}
+
+func (o *HTMLOptions) prefix() string {
+	if o.ClassPrefix == "" {
+		return "ansi-"
+	}
+	return o.ClassPrefix
+}
+
+// ToHTML converts styled terminal output into HTML. Text is escaped, styled
+// runs are wrapped into <span> elements, OSC 8 hyperlinks with allowed
+// schemes become <a> elements (see HTMLOptions.FileLinks). The result is
+// intended to be placed within a <pre> element.
+//
+// Inverse video is rendered with palette colors 7 (foreground) and 0
+// (background) in place of the terminal's default colors.
+func ToHTML(s string, opts *HTMLOptions) string {
+	if opts == nil {
+		opts = &HTMLOptions{}
+	}
+	palette := opts.Palette
+	if palette == nil {
+		p := StandardPalette()
+		palette = &p
+	}
+
+	var b strings.Builder
+	for _, seg := range segments(s) {
+		if seg.link != "" && !opts.allowLink(seg.link) {
+			seg.link = ""
+		}
+		if seg.link != "" {
+			b.WriteString(`<a href="` + html.EscapeString(seg.link) + `">`)
+		}
+		classes, css := htmlStyle(seg.style, opts, palette)
+		if classes != "" || css != "" {
+			b.WriteString("<span")
+			if classes != "" {
+				b.WriteString(` class="` + classes + `"`)
+			}
+			if css != "" {
+				b.WriteString(` style="` + css + `"`)
+			}
+			b.WriteString(">")
+			b.WriteString(html.EscapeString(seg.text))
+			b.WriteString("</span>")
+		} else {
+			b.WriteString(html.EscapeString(seg.text))
+		}
+		if seg.link != "" {
+			b.WriteString("</a>")
+		}
+	}
+	return b.String()
+}
+
+var htmlAttrs = []struct {
+	attr  Attr
+	class string
+	css   string
+}{
+	{AttrBold, "bold", "font-weight:bold"},
+	{AttrDim, "dim", "opacity:0.7"},
+	{AttrItalic, "italic", "font-style:italic"},
+	{AttrUnderline, "underline", "text-decoration-line:underline"},
+	{AttrDoubleUnderline, "double-underline", "text-decoration-line:underline;text-decoration-style:double"},
+	{AttrCurlyUnderline, "curly-underline", "text-decoration-line:underline;text-decoration-style:wavy"},
+	{AttrDottedUnderline, "dotted-underline", "text-decoration-line:underline;text-decoration-style:dotted"},
+	{AttrDashedUnderline, "dashed-underline", "text-decoration-line:underline;text-decoration-style:dashed"},
+	{AttrBlinking, "blink", "text-decoration-line:blink"},
+	{AttrHidden, "hidden", "visibility:hidden"},
+	{AttrStrikeThrough, "strikethrough", "text-decoration-line:line-through"},
+	{AttrOverline, "overline", "text-decoration-line:overline"},
+}
+
+func htmlStyle(st Style, opts *HTMLOptions, palette *Palette) (classes string, css string) {
+	var cls, rules []string
+	prefix := opts.prefix()
+
+	fg, bg := st.Fg, st.Bg
+	if st.Attrs&AttrInverse != 0 {
+		if fg.IsDefault() {
+			fg = Indexed(7)
+		}
+		if bg.IsDefault() {
+			bg = Indexed(0)
+		}
+		fg, bg = bg, fg
+	}
+	color := func(c Color, kind, prop string) {
+		if c.IsDefault() {
+			return
+		}
+		if i, ok := c.Index(); ok {
+			if opts.Classes {
+				cls = append(cls, prefix+kind+"-"+strconv.Itoa(int(i)))
+			} else {
+				rules = append(rules, prop+":"+palette[i].Hex())
+			}
+			return
+		}
+		v, _ := c.RGB()
+		rules = append(rules, prop+":"+v.Hex())
+	}
+	color(fg, "fg", "color")
+	color(bg, "bg", "background-color")
+
+	var decorations []string
+	for _, a := range htmlAttrs {
+		if st.Attrs&a.attr == 0 {
+			continue
+		}
+		if opts.Classes {
+			cls = append(cls, prefix+a.class)
+			continue
+		}
+		// combine text-decoration-line values into a single rule
+		for _, r := range strings.Split(a.css, ";") {
+			if v := strings.TrimPrefix(r, "text-decoration-line:"); v != r {
+				decorations = append(decorations, v)
+			} else {
+				rules = append(rules, r)
+			}
+		}
+	}
+	if len(decorations) > 0 {
+		rules = append(rules, "text-decoration-line:"+strings.Join(decorations, " "))
+	}
+	return strings.Join(cls, " "), strings.Join(rules, ";")
+}
+
+// HTMLStyleSheet produces CSS rules for the classes that ToHTML emits when
+// HTMLOptions.Classes is enabled
+func HTMLStyleSheet(opts *HTMLOptions) string {
+	if opts == nil {
+		opts = &HTMLOptions{}
+	}
+	palette := opts.Palette
+	if palette == nil {
+		p := StandardPalette()
+		palette = &p
+	}
+	prefix := opts.prefix()
+	var b strings.Builder
+	for _, a := range htmlAttrs {
+		b.WriteString("." + prefix + a.class + " { " + a.css + " }\n")
+	}
+	for i, c := range palette {
+		n := strconv.Itoa(i)
+		b.WriteString("." + prefix + "fg-" + n + " { color:" + c.Hex() + " }\n")
+	}
+	for i, c := range palette {
+		n := strconv.Itoa(i)
+		b.WriteString("." + prefix + "bg-" + n + " { background-color:" + c.Hex() + " }\n")
+	}
+	return b.String()
+}
+
+// markdownEscaper escapes characters that have special meaning in Markdown
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`, `~`, `\~`, `&`, `\&`,
+)
+
+// ToMarkdown converts styled terminal output into Markdown-safe plain text:
+// escape sequences are removed, characters that have special meaning in
+// Markdown are escaped, line breaks are preserved with hard breaks.
+func ToMarkdown(s string) string {
+	var b strings.Builder
+	for _, seg := range segments(s) {
+		b.WriteString(seg.text)
+	}
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		line = markdownEscaper.Replace(line)
+		// leading list markers, setext heading underlines, and ordered list
+		// numbers
+		if t := strings.TrimLeft(line, " "); len(t) > 0 && (t[0] == '-' || t[0] == '+' || t[0] == '=') {
+			line = line[:len(line)-len(t)] + `\` + t
+		} else if j := strings.IndexFunc(t, func(r rune) bool { return r < '0' || r > '9' }); j > 0 && t[j] == '.' {
+			line = line[:len(line)-len(t)] + t[:j] + `\` + t[j:]
+		}
+		// preserve leading indentation
+		if t := strings.TrimLeft(line, " "); len(t) < len(line) {
+			line = strings.Repeat("&nbsp;", len(line)-len(t)) + t
+		}
+		if i < len(lines)-1 && line != "" && lines[i+1] != "" {
+			line += "  "
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToMarkdownCodeBlock converts styled terminal output into a fenced Markdown
+// code block with escape sequences removed
+func ToMarkdownCodeBlock(s string) string {
+	var b strings.Builder
+	for _, seg := range segments(s) {
+		b.WriteString(seg.text)
+	}
+	text := strings.TrimSuffix(b.String(), "\n")
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + "\n" + text + "\n" + fence + "\n"
+}
@@ -0,0 +1,58 @@
+package ansi
+
+import "testing"
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		opts *HTMLOptions
+		want string
+	}{
+		{"plain", "a<b", nil, "a&lt;b"},
+		{"inline", "\x1b[1;31mred\x1b[0m ok", nil,
+			`<span style="color:#cd0000;font-weight:bold">red</span> ok`},
+		{"classes", "\x1b[1;38;5;200;48;2;1;2;3mx", &HTMLOptions{Classes: true},
+			`<span class="ansi-fg-200 ansi-bold" style="background-color:#010203">x</span>`},
+		{"decorations", "\x1b[4;9mx", nil,
+			`<span style="text-decoration-line:underline line-through">x</span>`},
+		{"inverse", "\x1b[7mx", nil,
+			`<span style="color:#000000;background-color:#e5e5e5">x</span>`},
+		{"link", "\x1b]8;;http://x?a&b\x1b\\x\x1b]8;;\x1b\\", nil,
+			`<a href="http://x?a&amp;b">x</a>`},
+		{"javascript-link", "\x1b]8;;javascript:alert(1)\x1b\\x\x1b]8;;\x1b\\", nil, "x"},
+		{"data-link", "\x1b]8;;DATA:text/html,<b>\x1b\\x\x1b]8;;\x1b\\", nil, "x"},
+		{"file-link", "\x1b]8;;file:///tmp/x\x1b\\x\x1b]8;;\x1b\\", nil, "x"},
+		{"file-link-allowed", "\x1b]8;;file:///tmp/x\x1b\\x\x1b]8;;\x1b\\", &HTMLOptions{FileLinks: true},
+			`<a href="file:///tmp/x">x</a>`},
+		{"carriage-return", "a\n10%\r50%\r100%\r\nb", nil, "a\n100%\nb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHTML(tt.s, tt.opts); got != tt.want {
+				t.Errorf("ToHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToMarkdown(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"\x1b[1m*bold*\x1b[0m", `\*bold\*`},
+		{"- item\n1. item", "\\- item  \n1\\. item"},
+		{"  indented", "&nbsp;&nbsp;indented"},
+		{"a\n\nb", "a\n\nb"},
+		{"title\n===\nsub\n---", "title  \n\\===  \nsub  \n\\---"},
+	}
+	for _, tt := range tests {
+		if got := ToMarkdown(tt.s); got != tt.want {
+			t.Errorf("ToMarkdown(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+	if got, want := ToMarkdownCodeBlock("a ```b```\n"), "````\na ```b```\n````\n"; got != want {
+		t.Errorf("ToMarkdownCodeBlock() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package ansi
+
+import "strconv"
+
+const (
+	SaveCursor        = "\x1b7"
+	RestoreCursor     = "\x1b8"
+	HideCursor        = "\x1b[?25l"
+	ShowCursor        = "\x1b[?25h"
+	EnterAltScreen    = "\x1b[?1049h"
+	ExitAltScreen     = "\x1b[?1049l"
+	ResetScrollRegion = "\x1b[r"
+)
+
+// EraseMode specifies which part of a line or screen is erased
+type EraseMode int
+
+const (
+	EraseToEnd   = EraseMode(0) // from cursor to the end
+	EraseToStart = EraseMode(1) // from the beginning to cursor
+	EraseAll     = EraseMode(2) // entire line or screen
+)
+
+func csi(n int, final string) string {
+	return "\x1b[" + strconv.Itoa(n) + final
+}
+
+// CursorUp moves cursor up by n lines
+func CursorUp(n int) string {
+	return csi(n, "A")
+}
+
+// CursorDown moves cursor down by n lines
+func CursorDown(n int) string {
+	return csi(n, "B")
+}
+
+// CursorForward moves cursor right by n columns
+func CursorForward(n int) string {
+	return csi(n, "C")
+}
+
+// CursorBack moves cursor left by n columns
+func CursorBack(n int) string {
+	return csi(n, "D")
+}
+
+// CursorNextLine moves cursor to the beginning of the line n lines down
+func CursorNextLine(n int) string {
+	return csi(n, "E")
+}
+
+// CursorPrevLine moves cursor to the beginning of the line n lines up
+func CursorPrevLine(n int) string {
+	return csi(n, "F")
+}
+
+// CursorColumn moves cursor to the specified column (1-based)
+func CursorColumn(col int) string {
+	return csi(col, "G")
+}
+
+// CursorPosition moves cursor to the specified row and column (1-based)
+func CursorPosition(row, col int) string {
+	return "\x1b[" + strconv.Itoa(row) + ";" + strconv.Itoa(col) + "H"
+}
+
+// EraseLine erases a part of the current line, cursor position does not
+// change
+func EraseLine(m EraseMode) string {
+	return csi(int(m), "K")
+}
+
+// EraseScreen erases a part of the screen, cursor position does not change
+func EraseScreen(m EraseMode) string {
+	return csi(int(m), "J")
+}
+
+// ScrollUp scrolls the content of the scroll region up by n lines
+func ScrollUp(n int) string {
+	return csi(n, "S")
+}
+
+// ScrollDown scrolls the content of the scroll region down by n lines
+func ScrollDown(n int) string {
+	return csi(n, "T")
+}
+
+// ScrollRegion limits scrolling to the lines from top to bottom (1-based,
+// inclusive), use ResetScrollRegion to restore full screen scrolling
+func ScrollRegion(top, bottom int) string {
+	return "\x1b[" + strconv.Itoa(top) + ";" + strconv.Itoa(bottom) + "r"
+}
@@ -0,0 +1,111 @@
+package ansi
+
+import (
+	"encoding/base64"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// stringTerminator terminates OSC sequences
+const stringTerminator = "\x1b\\"
+
+// sanitize removes control characters that would break out of an OSC
+// sequence
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Hyperlink produces an OSC 8 hyperlink that displays text and points to url
+func Hyperlink(url, text string) string {
+	return "\x1b]8;;" + sanitize(url) + stringTerminator + text + "\x1b]8;;" + stringTerminator
+}
+
+// FileURL constructs a file:// URL for a local path. The path is made
+// absolute, the host name is included as recommended by the OSC 8
+// specification.
+//
+// Non-zero line and column numbers (1-based) are appended as a fragment:
+// #line or #line:column.
+func FileURL(path string, line, col int) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // windows drive letters
+	}
+	u := url.URL{Scheme: "file", Path: path}
+	if host, err := os.Hostname(); err == nil {
+		u.Host = host
+	}
+	if line > 0 {
+		u.Fragment = strconv.Itoa(line)
+		if col > 0 {
+			u.Fragment += ":" + strconv.Itoa(col)
+		}
+	}
+	return u.String()
+}
+
+// WindowTitle produces an OSC 2 sequence that sets the window title
+func WindowTitle(title string) string {
+	return "\x1b]2;" + sanitize(title) + stringTerminator
+}
+
+// IconAndWindowTitle produces an OSC 0 sequence that sets both the icon name
+// and the window title
+func IconAndWindowTitle(title string) string {
+	return "\x1b]0;" + sanitize(title) + stringTerminator
+}
+
+// ClipboardCopy produces an OSC 52 sequence that copies data into the system
+// clipboard
+func ClipboardCopy(data string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(data)) + stringTerminator
+}
+
+// Hyperlink produces an OSC 8 hyperlink if the output supports it, otherwise
+// returns text as-is
+func (s *OutputState) Hyperlink(url, text string) string {
+	if !s.hyperlinks {
+		return text
+	}
+	return Hyperlink(url, text)
+}
+
+// FileLink produces a hyperlink to a local file location (see FileURL) if the
+// output supports it, otherwise returns text as-is
+//
+// Example:
+//
+//	loc := sourcecode.FileLocation{...}
+//	fmt.Println(s.FileLink(loc.String(), loc.Filename, loc.LineNumber, loc.ColumnNumber))
+func (s *OutputState) FileLink(text string, path string, line, col int) string {
+	if !s.hyperlinks {
+		return text
+	}
+	return Hyperlink(FileURL(path, line, col), text)
+}
+
+// SetTitle sets the window title if the output is a terminal
+func (s *OutputState) SetTitle(title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(WindowTitle(title))
+}
+
+// CopyToClipboard copies data into the system clipboard if the output is a
+// terminal, terminals that do not support OSC 52 ignore the request
+func (s *OutputState) CopyToClipboard(data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(ClipboardCopy(data))
+}
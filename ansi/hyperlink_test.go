@@ -0,0 +1,40 @@
+package ansi
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestFileURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("posix paths")
+	}
+	host, _ := os.Hostname()
+	tests := []struct {
+		path      string
+		line, col int
+		want      string
+	}{
+		{"/tmp/a b.go", 0, 0, "file://" + host + "/tmp/a%20b.go"},
+		{"/tmp/a.go", 12, 0, "file://" + host + "/tmp/a.go#12"},
+		{"/tmp/a.go", 12, 3, "file://" + host + "/tmp/a.go#12:3"},
+	}
+	for _, tt := range tests {
+		if got := FileURL(tt.path, tt.line, tt.col); got != tt.want {
+			t.Errorf("FileURL(%q, %d, %d) = %q, want %q", tt.path, tt.line, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestOutputStateHyperlink(t *testing.T) {
+	s := &OutputState{}
+	if got := s.Hyperlink("http://x", "x"); got != "x" {
+		t.Errorf("unsupported: %q", got)
+	}
+	s.hyperlinks = true
+	want := "\x1b]8;;http://x\x1b\\x\x1b]8;;\x1b\\"
+	if got := s.Hyperlink("http://x\x07", "x"); got != want {
+		t.Errorf("supported: %q, want %q", got, want)
+	}
+}
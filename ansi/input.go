@@ -0,0 +1,332 @@
+package ansi
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	EnableBracketedPaste  = "\x1b[?2004h"
+	DisableBracketedPaste = "\x1b[?2004l"
+)
+
+// InputState allows restoring terminal input to its original mode.
+//
+// The terminal stays in the modified mode after the program exits unless it
+// is restored: use `defer s.Restore()` for normal returns and panics, and
+// RestoreOnInterrupt for signals (in cbreak mode, Ctrl+C still terminates the
+// program).
+type InputState struct {
+	mu          sync.Mutex
+	restoreProc func()
+}
+
+// Restore returns the input to its original mode, can be called multiple
+// times; use `defer s.Restore()` to restore the input when the program panics
+func (s *InputState) Restore() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.restoreProc != nil {
+		s.restoreProc()
+		s.restoreProc = nil
+	}
+}
+
+// RestoreOnInterrupt installs a handler that restores the input and
+// terminates the program when it receives an interrupt (Ctrl+C) or
+// termination signal, see OutputState.RestoreOnInterrupt.
+//
+// Call the returned stop function to uninstall the handler.
+func (s *InputState) RestoreOnInterrupt() (stop func()) {
+	return onInterrupt(s.Restore)
+}
+
+// MakeRaw puts a terminal input into raw mode: input is available byte by
+// byte, without echo and line editing, and control characters (Ctrl+C, Ctrl+Z)
+// are delivered as input instead of generating signals.
+//
+// On Windows hosts, virtual terminal input is enabled, so that special keys are
+// delivered as escape sequences.
+func MakeRaw(input *os.File) (*InputState, error) {
+	proc, err := implMakeRaw(input, false)
+	if err != nil {
+		return nil, err
+	}
+	return &InputState{restoreProc: proc}, nil
+}
+
+// MakeCbreak puts a terminal input into cbreak mode: similar to raw mode, but
+// Ctrl+C and other control characters still generate signals, so call
+// RestoreOnInterrupt to restore the input when the program is interrupted.
+func MakeCbreak(input *os.File) (*InputState, error) {
+	proc, err := implMakeRaw(input, true)
+	if err != nil {
+		return nil, err
+	}
+	return &InputState{restoreProc: proc}, nil
+}
+
+// EnableBracketedPaste asks the terminal to mark pasted text, so that it is
+// decoded as a single KeyPaste event; it will be disabled by Restore
+func (s *OutputState) EnableBracketedPaste() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(EnableBracketedPaste)
+	s.bracketedPaste = s.supported
+}
+
+// DisableBracketedPaste disables bracketed paste mode
+func (s *OutputState) DisableBracketedPaste() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(DisableBracketedPaste)
+	s.bracketedPaste = false
+}
+
+// WatchResize calls fn with the new terminal size whenever the terminal is
+// resized (uses SIGWINCH on posix hosts, polling on Windows hosts). Call the
+// returned stop function to stop watching.
+func (s *OutputState) WatchResize(fn func(cols, rows int)) (stop func()) {
+	return implWatchResize(s, func() {
+		if cols, rows, ok := s.Size(); ok {
+			fn(cols, rows)
+		}
+	})
+}
+
+// Key identifies a key on the keyboard
+type Key int
+
+const (
+	KeyRune    = Key(iota) // a character, see KeyEvent.Rune
+	KeyUnknown             // unrecognized escape sequence
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyEscape
+	KeyUp
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyInsert
+	KeyDelete
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+	KeyPaste // bracketed paste, see KeyEvent.Text
+)
+
+// Modifier is a set of modifier keys held down with a key
+type Modifier uint8
+
+const (
+	ModShift = Modifier(1 << iota)
+	ModAlt
+	ModCtrl
+)
+
+// KeyEvent is a decoded key press
+type KeyEvent struct {
+	Key  Key
+	Rune rune // KeyRune: the character; for Ctrl+letter this is the lowercase letter
+	Mod  Modifier
+	Text string // KeyPaste: pasted text
+	Raw  string // input bytes that produced the event
+}
+
+const (
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+)
+
+var tildeKeys = map[int]Key{
+	1: KeyHome, 2: KeyInsert, 3: KeyDelete, 4: KeyEnd, 5: KeyPageUp, 6: KeyPageDown,
+	7: KeyHome, 8: KeyEnd,
+	11: KeyF1, 12: KeyF2, 13: KeyF3, 14: KeyF4, 15: KeyF5,
+	17: KeyF6, 18: KeyF7, 19: KeyF8, 20: KeyF9, 21: KeyF10,
+	23: KeyF11, 24: KeyF12,
+}
+
+var letterKeys = map[byte]Key{
+	'A': KeyUp, 'B': KeyDown, 'C': KeyRight, 'D': KeyLeft,
+	'H': KeyHome, 'F': KeyEnd,
+	'P': KeyF1, 'Q': KeyF2, 'R': KeyF3, 'S': KeyF4,
+}
+
+// DecodeKey decodes a key event at the beginning of b.
+//
+// Returns n=0 if b contains an incomplete sequence and more input is needed.
+// A lone ESC byte is reported as incomplete, since it may be a beginning of an
+// escape sequence, see KeyReader for disambiguation.
+func DecodeKey(b []byte) (ev KeyEvent, n int) {
+	if len(b) == 0 {
+		return ev, 0
+	}
+	defer func() {
+		if n > 0 {
+			ev.Raw = string(b[:n])
+		}
+	}()
+
+	c := b[0]
+	switch {
+	case c == esc:
+		return decodeEscape(b)
+	case c == '\r' || c == '\n':
+		return KeyEvent{Key: KeyEnter}, 1
+	case c == '\t':
+		return KeyEvent{Key: KeyTab}, 1
+	case c == 0x7f:
+		return KeyEvent{Key: KeyBackspace}, 1
+	case c == 0x08:
+		return KeyEvent{Key: KeyBackspace, Mod: ModCtrl}, 1
+	case c == 0:
+		return KeyEvent{Key: KeyRune, Rune: ' ', Mod: ModCtrl}, 1
+	case c < 0x1b:
+		return KeyEvent{Key: KeyRune, Rune: rune('a' + c - 1), Mod: ModCtrl}, 1
+	case c < 0x20:
+		return KeyEvent{Key: KeyRune, Rune: rune(`\]^_`[c-0x1c]), Mod: ModCtrl}, 1
+	}
+	if !utf8.FullRune(b) {
+		return ev, 0
+	}
+	r, n := utf8.DecodeRune(b)
+	return KeyEvent{Key: KeyRune, Rune: r}, n
+}
+
+func decodeEscape(b []byte) (ev KeyEvent, n int) {
+	if len(b) < 2 {
+		return ev, 0
+	}
+	switch b[1] {
+	case '[':
+		return decodeCSIKey(b)
+	case 'O':
+		if len(b) < 3 {
+			return ev, 0
+		}
+		if k, ok := letterKeys[b[2]]; ok {
+			return KeyEvent{Key: k}, 3
+		}
+		return KeyEvent{Key: KeyUnknown}, 3
+	case esc:
+		// ESC ESC ... is Alt+<sequence>, or Alt+Escape
+		if len(b) == 2 {
+			return KeyEvent{Key: KeyEscape, Mod: ModAlt}, 2
+		}
+		ev, n = decodeEscape(b[1:])
+		if n == 0 {
+			return ev, 0
+		}
+		ev.Mod |= ModAlt
+		return ev, n + 1
+	}
+	// Alt+key
+	ev, n = DecodeKey(b[1:])
+	if n == 0 {
+		return ev, 0
+	}
+	ev.Mod |= ModAlt
+	return ev, n + 1
+}
+
+func decodeCSIKey(b []byte) (ev KeyEvent, n int) {
+	if strings.HasPrefix(string(b), pasteStart) {
+		i := strings.Index(string(b[len(pasteStart):]), pasteEnd)
+		if i < 0 {
+			return ev, 0
+		}
+		text := string(b[len(pasteStart) : len(pasteStart)+i])
+		return KeyEvent{Key: KeyPaste, Text: text}, len(pasteStart) + i + len(pasteEnd)
+	}
+	n, kind := scanSequence(b)
+	if n == 0 {
+		return ev, 0
+	}
+	if kind != seqCSI {
+		return KeyEvent{Key: KeyUnknown}, n
+	}
+	final := b[n-1]
+	params := strings.Split(string(b[2:n-1]), ";")
+	num := func(i int, def int) int {
+		if i >= len(params) {
+			return def
+		}
+		v, err := strconv.Atoi(params[i])
+		if err != nil {
+			return def
+		}
+		return v
+	}
+	mod := Modifier(0)
+	if m := num(1, 1); m > 1 {
+		mod = Modifier(m-1) & (ModShift | ModAlt | ModCtrl)
+	}
+	switch {
+	case final == '~':
+		if k, ok := tildeKeys[num(0, 0)]; ok {
+			return KeyEvent{Key: k, Mod: mod}, n
+		}
+	case final == 'Z':
+		return KeyEvent{Key: KeyTab, Mod: ModShift}, n
+	default:
+		if k, ok := letterKeys[final]; ok {
+			return KeyEvent{Key: k, Mod: mod}, n
+		}
+	}
+	return KeyEvent{Key: KeyUnknown}, n
+}
+
+// KeyReader reads and decodes key events from a terminal input, which is
+// expected to be in raw or cbreak mode.
+type KeyReader struct {
+	r   io.Reader
+	buf []byte
+	tmp [256]byte
+}
+
+// NewKeyReader creates a KeyReader for r
+func NewKeyReader(r io.Reader) *KeyReader {
+	return &KeyReader{r: r}
+}
+
+// ReadKey blocks until a key event is available.
+//
+// An ESC byte that arrives at the end of a read is reported as the Escape key,
+// since terminals send escape sequences in a single write.
+func (kr *KeyReader) ReadKey() (KeyEvent, error) {
+	for {
+		if len(kr.buf) > 0 {
+			ev, n := DecodeKey(kr.buf)
+			if n == 0 && len(kr.buf) == 1 && kr.buf[0] == esc {
+				ev, n = KeyEvent{Key: KeyEscape, Raw: "\x1b"}, 1
+			}
+			if n > 0 {
+				kr.buf = kr.buf[n:]
+				return ev, nil
+			}
+		}
+		n, err := kr.r.Read(kr.tmp[:])
+		kr.buf = append(kr.buf, kr.tmp[:n]...)
+		if err != nil {
+			return KeyEvent{}, err
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package ansi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeKey(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  KeyEvent
+		wantN int
+	}{
+		{"a", KeyEvent{Key: KeyRune, Rune: 'a'}, 1},
+		{"ж", KeyEvent{Key: KeyRune, Rune: 'ж'}, 2},
+		{"\r", KeyEvent{Key: KeyEnter}, 1},
+		{"\x7f", KeyEvent{Key: KeyBackspace}, 1},
+		{"\x03", KeyEvent{Key: KeyRune, Rune: 'c', Mod: ModCtrl}, 1},
+		{"\x1bx", KeyEvent{Key: KeyRune, Rune: 'x', Mod: ModAlt}, 2},
+		{"\x1b[A", KeyEvent{Key: KeyUp}, 3},
+		{"\x1bOB", KeyEvent{Key: KeyDown}, 3},
+		{"\x1b[1;5C", KeyEvent{Key: KeyRight, Mod: ModCtrl}, 6},
+		{"\x1b[1;4D", KeyEvent{Key: KeyLeft, Mod: ModShift | ModAlt}, 6},
+		{"\x1b[3~", KeyEvent{Key: KeyDelete}, 4},
+		{"\x1b[5;2~", KeyEvent{Key: KeyPageUp, Mod: ModShift}, 6},
+		{"\x1b[15~", KeyEvent{Key: KeyF5}, 5},
+		{"\x1bOP", KeyEvent{Key: KeyF1}, 3},
+		{"\x1b[Z", KeyEvent{Key: KeyTab, Mod: ModShift}, 3},
+		{"\x1b[200~a\x1b[Bb\x1b[201~", KeyEvent{Key: KeyPaste, Text: "a\x1b[Bb"}, 17},
+		{"\x1b[99x", KeyEvent{Key: KeyUnknown}, 5},
+		{"\x1b", KeyEvent{}, 0},
+		{"\x1b[1;5", KeyEvent{}, 0},
+		{"\x1b[200~abc", KeyEvent{}, 0},
+		{"\xd0", KeyEvent{}, 0},
+	}
+	for _, tt := range tests {
+		got, n := DecodeKey([]byte(tt.in))
+		if n > 0 {
+			tt.want.Raw = tt.in[:n]
+		}
+		if got != tt.want || n != tt.wantN {
+			t.Errorf("DecodeKey(%q) = %+v, %d, want %+v, %d", tt.in, got, n, tt.want, tt.wantN)
+		}
+	}
+}
+
+func TestKeyReader(t *testing.T) {
+	kr := NewKeyReader(strings.NewReader("a\x1b[A\x1b"))
+	want := []Key{KeyRune, KeyUp, KeyEscape}
+	for _, k := range want {
+		ev, err := kr.ReadKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Key != k {
+			t.Errorf("ReadKey() = %+v, want key %d", ev, k)
+		}
+	}
+}
+
+func TestRestoreOnInterrupt(t *testing.T) {
+	var order []string
+	in := &InputState{restoreProc: func() { order = append(order, "input") }}
+	out := &OutputState{}
+	stopOut := out.RestoreOnInterrupt()
+	stopIn := in.RestoreOnInterrupt()
+	stopOther := onInterrupt(func() { order = append(order, "other") })
+	stopOther()
+	stopOther()
+
+	restoreAll()
+	if len(order) != 1 || order[0] != "input" {
+		t.Errorf("restored: %v", order)
+	}
+	in.Restore() // already restored, must not call the procedure again
+	if len(order) != 1 {
+		t.Errorf("restored twice: %v", order)
+	}
+
+	stopIn()
+	stopOut()
+	if interrupts.ch != nil {
+		t.Error("signal handler must be uninstalled with the last registration")
+	}
+}
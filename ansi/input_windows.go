@@ -0,0 +1,61 @@
+package ansi
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	cEnableProcessedInput       = uint32(0x1)
+	cEnableLineInput            = uint32(0x2)
+	cEnableEchoInput            = uint32(0x4)
+	cEnableVirtualTerminalInput = uint32(0x200)
+)
+
+func implMakeRaw(f *os.File, cbreak bool) (restore func(), err error) {
+	fd := f.Fd()
+	old, err := getMode(fd)
+	if err != nil {
+		return nil, err
+	}
+	m := old&^(cEnableLineInput|cEnableEchoInput) | cEnableVirtualTerminalInput
+	if !cbreak {
+		m &^= cEnableProcessedInput
+	}
+	if err = setMode(fd, m); err != nil {
+		return nil, err
+	}
+	return func() {
+		setMode(fd, old)
+	}, nil
+}
+
+// resizePollInterval is used for detecting console size changes, since
+// windows consoles report resizing only through console input events
+const resizePollInterval = 250 * time.Millisecond
+
+func implWatchResize(s *OutputState, notify func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(resizePollInterval)
+		defer t.Stop()
+		cols, rows, _ := s.Size()
+		for {
+			select {
+			case <-t.C:
+				c, r, ok := s.Size()
+				if ok && (c != cols || r != rows) {
+					cols, rows = c, r
+					notify()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
@@ -0,0 +1,268 @@
+package ansi
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LiveRegion is a block of status lines at the bottom of the output that is
+// redrawn in place. Lines can be updated concurrently from multiple
+// goroutines.
+//
+// On outputs that are not terminals, the region degrades to plain log lines:
+// changed lines are printed periodically (see SetLogInterval), and when they
+// are marked as done.
+type LiveRegion struct {
+	state *OutputState
+	w     io.Writer
+	tty   bool
+
+	mu          sync.Mutex
+	logInterval time.Duration
+	lines       []*LiveLine
+	drawn       int
+	lastLog     time.Time
+	stop        chan struct{}
+	done        chan struct{}
+	stopOnce    sync.Once
+}
+
+// LiveLine is a single line in a LiveRegion
+type LiveLine struct {
+	region *LiveRegion
+	text   string
+	render func(width int) string // dynamic content (progress bars)
+	logged string                 // last text printed in log mode
+	final  bool
+}
+
+// RefreshInterval is the redraw period for live regions on terminals
+const RefreshInterval = 100 * time.Millisecond
+
+// NewLiveRegion creates a live region on the output of s and starts
+// redrawing it in the background; call Stop when done. A nil s produces plain
+// log lines on os.Stdout.
+func NewLiveRegion(s *OutputState) *LiveRegion {
+	var w io.Writer = os.Stdout
+	if s != nil && s.File() != nil {
+		w = s.File()
+	}
+	return newLiveRegion(s, w)
+}
+
+func newLiveRegion(s *OutputState, w io.Writer) *LiveRegion {
+	r := &LiveRegion{
+		state:       s,
+		w:           w,
+		tty:         s != nil && s.Supported() && s.IsTerminal(),
+		logInterval: 10 * time.Second,
+		lastLog:     time.Now(),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	if r.tty {
+		s.HideCursor()
+	}
+	go r.run()
+	return r
+}
+
+// SetLogInterval changes the period of logging changed lines on outputs that
+// are not terminals, defaults to 10s
+func (r *LiveRegion) SetLogInterval(d time.Duration) {
+	r.mu.Lock()
+	r.logInterval = d
+	r.mu.Unlock()
+}
+
+// AddLine appends a new line to the region
+func (r *LiveRegion) AddLine(text string) *LiveLine {
+	l := &LiveLine{region: r, text: text}
+	r.mu.Lock()
+	r.lines = append(r.lines, l)
+	r.mu.Unlock()
+	return l
+}
+
+func (r *LiveRegion) addRenderLine(render func(width int) string) *LiveLine {
+	l := &LiveLine{region: r, render: render}
+	r.mu.Lock()
+	r.lines = append(r.lines, l)
+	r.mu.Unlock()
+	return l
+}
+
+// Set replaces the line content
+func (l *LiveLine) Set(text string) {
+	l.region.mu.Lock()
+	l.text = text
+	l.render = nil
+	l.region.mu.Unlock()
+}
+
+// Setf replaces the line content with formatted text
+func (l *LiveLine) Setf(format string, a ...interface{}) {
+	l.Set(fmt.Sprintf(format, a...))
+}
+
+// Done replaces the line content and marks it as final: the line moves above
+// the region on the next redraw and is no longer updated. On outputs that are
+// not terminals the line is printed immediately.
+func (l *LiveLine) Done(text string) {
+	l.finish(func() {
+		l.text = text
+		l.render = nil
+	})
+}
+
+// finish marks the line as final after applying the optional update
+func (l *LiveLine) finish(update func()) {
+	r := l.region
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if update != nil {
+		update()
+	}
+	l.final = true
+	if !r.tty {
+		r.logLine(l)
+		r.removeFinal()
+	}
+}
+
+// removeFinal drops final lines from the region
+func (r *LiveRegion) removeFinal() {
+	live := r.lines[:0]
+	for _, l := range r.lines {
+		if !l.final {
+			live = append(live, l)
+		}
+	}
+	for i := len(live); i < len(r.lines); i++ {
+		r.lines[i] = nil
+	}
+	r.lines = live
+}
+
+// Println prints a permanent line above the region
+func (r *LiveRegion) Println(a ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := fmt.Sprintln(a...)
+	if !r.tty {
+		io.WriteString(r.w, s)
+		return
+	}
+	r.clear()
+	io.WriteString(r.w, s)
+	r.draw()
+}
+
+// Stop stops background redrawing and leaves the final state of the lines in
+// the output
+func (r *LiveRegion) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.tty {
+			r.draw()
+			r.state.ShowCursor()
+		} else {
+			for _, l := range r.lines {
+				r.logLine(l)
+			}
+		}
+	})
+}
+
+func (r *LiveRegion) run() {
+	defer close(r.done)
+	t := time.NewTicker(RefreshInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-t.C:
+			r.mu.Lock()
+			if r.tty {
+				r.draw()
+			} else if now.Sub(r.lastLog) >= r.logInterval {
+				r.lastLog = now
+				for _, l := range r.lines {
+					r.logLine(l)
+				}
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+func (l *LiveLine) content(width int) string {
+	if l.render != nil {
+		return l.render(width)
+	}
+	return l.text
+}
+
+// logLine prints a line in log mode if it changed since the last time
+func (r *LiveRegion) logLine(l *LiveLine) {
+	s := l.content(0)
+	if s == l.logged || s == "" {
+		return
+	}
+	l.logged = s
+	io.WriteString(r.w, Strip(s)+"\n")
+}
+
+// clear moves the cursor to the first line of the region and erases the
+// region
+func (r *LiveRegion) clear() {
+	if r.drawn > 0 {
+		io.WriteString(r.w, CursorPrevLine(r.drawn)+EraseScreen(EraseToEnd))
+		r.drawn = 0
+	}
+}
+
+// draw redraws the region in place, final lines are printed above it and
+// dropped from the region
+func (r *LiveRegion) draw() {
+	width, _, ok := r.state.Size()
+	if !ok {
+		width = 80
+	}
+	var b strings.Builder
+	if r.drawn > 0 {
+		b.WriteString(CursorPrevLine(r.drawn))
+	}
+	line := func(l *LiveLine) {
+		b.WriteString(EraseLine(EraseAll))
+		// leave the last column empty to prevent auto-wrapping
+		b.WriteString(Truncate(l.content(width-1), width-1, "…"))
+		b.WriteString("\n")
+	}
+	written := 0
+	for _, l := range r.lines {
+		if l.final {
+			line(l)
+			written++
+		}
+	}
+	r.removeFinal()
+	for _, l := range r.lines {
+		line(l)
+	}
+	written += len(r.lines)
+	if written < r.drawn {
+		b.WriteString(EraseScreen(EraseToEnd))
+	}
+	r.drawn = len(r.lines)
+	io.WriteString(r.w, b.String())
+}
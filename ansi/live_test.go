@@ -0,0 +1,124 @@
+package ansi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer collects output written from multiple goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLiveRegionConcurrent(t *testing.T) {
+	out := &syncBuffer{}
+	r := newLiveRegion(nil, out)
+	r.SetLogInterval(time.Millisecond)
+
+	p := r.AddProgress("copy", 1000)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		l := r.AddLine("")
+		wg.Add(1)
+		go func(i int, l *LiveLine) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.Setf("worker %d: %d", i, j)
+				p.Add(5)
+				p.SetLabel(fmt.Sprintf("copy %d", j))
+				p.SetWidth(10 + j)
+				if j%10 == 0 {
+					r.Println("log", i, j)
+				}
+				time.Sleep(100 * time.Microsecond)
+			}
+			l.Done(fmt.Sprintf("worker %d: done", i))
+		}(i, l)
+	}
+	wg.Wait()
+	p.SetLabel("copy")
+	p.SetBytes(false)
+	p.Finish("")
+	r.Stop()
+	r.Stop() // idempotent
+
+	s := out.String()
+	for i := 0; i < 4; i++ {
+		if !strings.Contains(s, fmt.Sprintf("worker %d: done\n", i)) {
+			t.Errorf("missing final line for worker %d", i)
+		}
+	}
+	if !strings.Contains(s, "log 0 0\n") || !strings.Contains(s, "copy 100% 1000/1000") {
+		t.Errorf("unexpected output:\n%s", s)
+	}
+	if strings.Contains(s, "\x1b") {
+		t.Error("plain output must not contain escape sequences")
+	}
+}
+
+func TestNewLiveRegionNil(t *testing.T) {
+	r := NewLiveRegion(nil)
+	r.AddLine("")
+	r.Stop()
+}
+
+func TestLiveRegionFinalLines(t *testing.T) {
+	out := &syncBuffer{}
+	r := newLiveRegion(&OutputState{supported: true, tty: true}, out)
+	status := r.AddLine("status")
+	for i := 0; i < 100; i++ {
+		r.AddLine("").Done(fmt.Sprintf("item %d", i))
+	}
+	p := r.AddProgress("copy", 10)
+	p.Set(10)
+	p.Finish("")
+
+	r.mu.Lock()
+	r.draw()
+	n := len(r.lines)
+	r.mu.Unlock()
+	if n != 1 {
+		t.Errorf("final lines must leave the region, %d lines left", n)
+	}
+	status.Done("finished")
+	r.Stop()
+
+	s := out.String()
+	if !strings.Contains(s, "item 99\n") || !strings.Contains(s, "finished\n") {
+		t.Errorf("missing final lines:\n%s", s)
+	}
+	if !strings.Contains(s, "copy "+strings.Repeat("█", 30)+" 100%") {
+		t.Errorf("finished bar must keep its last state:\n%s", s)
+	}
+}
+
+func TestLiveRegionConcurrentStop(t *testing.T) {
+	r := newLiveRegion(nil, &syncBuffer{})
+	r.AddLine("line")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Stop()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,312 @@
+package ansi
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenKind classifies tokens produced by Parser
+type TokenKind int
+
+const (
+	TokenText    = TokenKind(iota) // printable text, including '\n' and '\t'
+	TokenControl                   // other C0 control characters ('\r', '\b', BEL, ...)
+	TokenSGR                       // select graphic rendition (styling) sequence
+	TokenCSI                       // other control sequences (cursor movement, erasing, ...)
+	TokenOSC                       // operating system command (hyperlinks, titles, ...)
+	TokenEscape                    // other escape sequences, including malformed ones
+)
+
+// Token is a piece of parsed terminal output
+type Token struct {
+	Kind TokenKind
+	Raw  string // original content of the token
+
+	// Style is the style in effect for TokenText, or the resulting style after
+	// applying TokenSGR
+	Style Style
+
+	// Link is the target of an OSC 8 hyperlink in effect for TokenText
+	Link string
+
+	// TokenSGR and TokenCSI: parameters, private marker ('?', '>', '<', '=',
+	// or 0), and final byte, e.g. for "\x1b[?25l": Params="25", Private='?',
+	// Final='l'
+	Params  string
+	Private byte
+	Final   byte
+
+	// TokenOSC: command number and payload, e.g. for "\x1b]2;title\x07":
+	// Command=2, Data="title"
+	Command int
+	Data    string
+}
+
+// Param returns the i-th numeric parameter of a TokenSGR or TokenCSI token,
+// or def if the parameter is missing or empty
+func (t *Token) Param(i int, def int) int {
+	params := strings.Split(t.Params, ";")
+	if i < 0 || i >= len(params) {
+		return def
+	}
+	p := params[i]
+	if j := strings.IndexByte(p, ':'); j >= 0 {
+		p = p[:j]
+	}
+	v, err := strconv.Atoi(p)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// Parser is a streaming tokenizer for terminal output. It keeps track of the
+// current style and hyperlink, sequences that are split across multiple Parse
+// calls are buffered until complete.
+type Parser struct {
+	style   Style
+	link    string
+	pending []byte
+}
+
+// NewParser creates a parser with the default style
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// Style returns the current style
+func (p *Parser) Style() Style {
+	return p.style
+}
+
+// Tokenize parses a complete string
+func Tokenize(s string) []Token {
+	var tokens []Token
+	p := NewParser()
+	emit := func(t Token) { tokens = append(tokens, t) }
+	p.Parse([]byte(s), emit)
+	p.Flush(emit)
+	return tokens
+}
+
+// Parse tokenizes data and calls emit for every complete token
+func (p *Parser) Parse(data []byte, emit func(Token)) {
+	if len(p.pending) > 0 {
+		data = append(p.pending, data...)
+		p.pending = nil
+	}
+	for len(data) > 0 {
+		c := data[0]
+		switch {
+		case c == esc:
+			n, kind := scanSequence(data)
+			if n == 0 {
+				if len(data) <= maxPending {
+					p.pending = append([]byte(nil), data...)
+					return
+				}
+				n, kind = 1, seqInvalid
+			}
+			emit(p.sequence(string(data[:n]), kind))
+			data = data[n:]
+		case c < 0x20 && c != '\n' && c != '\t', c == 0x7f:
+			emit(Token{Kind: TokenControl, Raw: string(c)})
+			data = data[1:]
+		default:
+			n := 1
+			for n < len(data) {
+				c := data[n]
+				if c == esc || (c < 0x20 && c != '\n' && c != '\t') || c == 0x7f {
+					break
+				}
+				n++
+			}
+			if n == len(data) {
+				// keep an incomplete trailing rune for the next call
+				if k := incompleteRune(data); k > 0 {
+					p.pending = append([]byte(nil), data[n-k:]...)
+					n -= k
+				}
+			}
+			if n > 0 {
+				emit(Token{Kind: TokenText, Raw: string(data[:n]), Style: p.style, Link: p.link})
+			}
+			if len(p.pending) > 0 {
+				return
+			}
+			data = data[n:]
+		}
+	}
+}
+
+// Flush emits buffered incomplete data as a TokenEscape or TokenText token
+func (p *Parser) Flush(emit func(Token)) {
+	if len(p.pending) == 0 {
+		return
+	}
+	raw := string(p.pending)
+	p.pending = nil
+	if raw[0] == esc {
+		emit(Token{Kind: TokenEscape, Raw: raw})
+	} else {
+		emit(Token{Kind: TokenText, Raw: raw, Style: p.style, Link: p.link})
+	}
+}
+
+// incompleteRune returns the length of an incomplete UTF-8 sequence at the
+// end of b
+func incompleteRune(b []byte) int {
+	for k := 1; k <= 3 && k <= len(b); k++ {
+		c := b[len(b)-k]
+		if !utf8.RuneStart(c) {
+			continue
+		}
+		if !utf8.FullRune(b[len(b)-k:]) {
+			return k
+		}
+		return 0
+	}
+	return 0
+}
+
+func (p *Parser) sequence(raw string, kind seqKind) Token {
+	t := Token{Raw: raw}
+	switch kind {
+	case seqCSI:
+		t.Kind = TokenCSI
+		t.Final = raw[len(raw)-1]
+		params := raw[2 : len(raw)-1]
+		if params != "" && params[0] >= '<' && params[0] <= '?' {
+			t.Private = params[0]
+			params = params[1:]
+		}
+		t.Params = params
+		if isSGR(raw) {
+			t.Kind = TokenSGR
+			p.style = p.style.ApplySGR(params)
+			t.Style = p.style
+		}
+	case seqOSC:
+		t.Kind = TokenOSC
+		body := strings.TrimSuffix(strings.TrimSuffix(raw[2:], "\x07"), stringTerminator)
+		cmd := body
+		if i := strings.IndexByte(body, ';'); i >= 0 {
+			cmd, t.Data = body[:i], body[i+1:]
+		}
+		t.Command, _ = strconv.Atoi(cmd)
+		if t.Command == 8 {
+			// 8;params;url
+			if i := strings.IndexByte(t.Data, ';'); i >= 0 {
+				p.link = t.Data[i+1:]
+			}
+		}
+	default:
+		t.Kind = TokenEscape
+	}
+	return t
+}
+
+// ApplySGR returns the style that results from applying SGR parameters (the
+// part between "\x1b[" and "m") to s
+func (s Style) ApplySGR(params string) Style {
+	if params == "" {
+		return Style{}
+	}
+	groups := strings.Split(params, ";")
+	for i := 0; i < len(groups); i++ {
+		sub := strings.Split(groups[i], ":")
+		code := 0
+		if sub[0] != "" {
+			var err error
+			if code, err = strconv.Atoi(sub[0]); err != nil {
+				continue
+			}
+		}
+		switch {
+		case code == 0:
+			s = Style{}
+		case code == 1:
+			s.Attrs |= AttrBold
+		case code == 2:
+			s.Attrs |= AttrDim
+		case code == 3:
+			s.Attrs |= AttrItalic
+		case code == 4:
+			s.Attrs &^= AttrAnyUnderline
+			variant := "1"
+			if len(sub) > 1 {
+				variant = sub[1]
+			}
+			switch variant {
+			case "1":
+				s.Attrs |= AttrUnderline
+			case "2":
+				s.Attrs |= AttrDoubleUnderline
+			case "3":
+				s.Attrs |= AttrCurlyUnderline
+			case "4":
+				s.Attrs |= AttrDottedUnderline
+			case "5":
+				s.Attrs |= AttrDashedUnderline
+			}
+		case code == 5 || code == 6:
+			s.Attrs |= AttrBlinking
+		case code == 7:
+			s.Attrs |= AttrInverse
+		case code == 8:
+			s.Attrs |= AttrHidden
+		case code == 9:
+			s.Attrs |= AttrStrikeThrough
+		case code == 21:
+			s.Attrs = s.Attrs&^AttrAnyUnderline | AttrDoubleUnderline
+		case code == 22:
+			s.Attrs &^= AttrBold | AttrDim
+		case code == 23:
+			s.Attrs &^= AttrItalic
+		case code == 24:
+			s.Attrs &^= AttrAnyUnderline
+		case code == 25:
+			s.Attrs &^= AttrBlinking
+		case code == 27:
+			s.Attrs &^= AttrInverse
+		case code == 28:
+			s.Attrs &^= AttrHidden
+		case code == 29:
+			s.Attrs &^= AttrStrikeThrough
+		case code == 53:
+			s.Attrs |= AttrOverline
+		case code == 55:
+			s.Attrs &^= AttrOverline
+		case code >= 30 && code <= 37:
+			s.Fg = Indexed(ColorIndex(code - 30))
+		case code >= 90 && code <= 97:
+			s.Fg = Indexed(ColorIndex(code - 90 + 8))
+		case code == 39:
+			s.Fg = DefaultColor
+		case code >= 40 && code <= 47:
+			s.Bg = Indexed(ColorIndex(code - 40))
+		case code >= 100 && code <= 107:
+			s.Bg = Indexed(ColorIndex(code - 100 + 8))
+		case code == 49:
+			s.Bg = DefaultColor
+		case code == 38 || code == 48 || code == 58:
+			var c extColor
+			if len(sub) > 1 {
+				c = parseExtColor(sub[1:], true)
+			} else {
+				c = parseExtColor(groups[i+1:], false)
+				i += c.consumed
+			}
+			if !c.valid {
+				continue
+			}
+			if code == 38 {
+				s.Fg = c.color()
+			} else if code == 48 {
+				s.Bg = c.color()
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+package ansi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	bold := Style{Attrs: AttrBold}
+	boldRed := Style{Fg: Indexed(1), Attrs: AttrBold}
+	got := Tokenize("a\x1b[1mb\x1b[31mc\r\x1b[2K\x1b]8;;http://x\x1b\\d\x1b]8;;\x1b\\\x1b[0m\x1b(B")
+	want := []Token{
+		{Kind: TokenText, Raw: "a"},
+		{Kind: TokenSGR, Raw: "\x1b[1m", Style: bold, Params: "1", Final: 'm'},
+		{Kind: TokenText, Raw: "b", Style: bold},
+		{Kind: TokenSGR, Raw: "\x1b[31m", Style: boldRed, Params: "31", Final: 'm'},
+		{Kind: TokenText, Raw: "c", Style: boldRed},
+		{Kind: TokenControl, Raw: "\r"},
+		{Kind: TokenCSI, Raw: "\x1b[2K", Params: "2", Final: 'K'},
+		{Kind: TokenOSC, Raw: "\x1b]8;;http://x\x1b\\", Command: 8, Data: ";http://x"},
+		{Kind: TokenText, Raw: "d", Style: boldRed, Link: "http://x"},
+		{Kind: TokenOSC, Raw: "\x1b]8;;\x1b\\", Command: 8, Data: ";"},
+		{Kind: TokenSGR, Raw: "\x1b[0m", Params: "0", Final: 'm'},
+		{Kind: TokenEscape, Raw: "\x1b(B"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for i := range got {
+			t.Logf("%d: %+v", i, got[i])
+		}
+		t.Errorf("Tokenize() mismatch")
+	}
+}
+
+func TestParserSplitInput(t *testing.T) {
+	input := "日本\x1b[38;2;1;2;3mtext\x1b[?25l"
+	var tokens []Token
+	p := NewParser()
+	emit := func(t Token) { tokens = append(tokens, t) }
+	for i := 0; i < len(input); i++ {
+		p.Parse([]byte{input[i]}, emit)
+	}
+	p.Flush(emit)
+
+	text := ""
+	for _, tok := range tokens {
+		if tok.Kind == TokenText {
+			text += tok.Raw
+		}
+	}
+	if text != "日本text" {
+		t.Errorf("text = %q", text)
+	}
+	last := tokens[len(tokens)-1]
+	if last.Kind != TokenCSI || last.Private != '?' || last.Param(0, 0) != 25 || last.Final != 'l' {
+		t.Errorf("last token = %+v", last)
+	}
+	if want := (Style{Fg: RGB24(1, 2, 3)}); p.Style() != want {
+		t.Errorf("Style() = %+v, want %+v", p.Style(), want)
+	}
+}
+
+func TestApplySGR(t *testing.T) {
+	tests := []struct {
+		params string
+		want   Style
+	}{
+		{"", Style{}},
+		{"1;4:3;38;5;200;48;2;1;2;3", Style{Fg: Indexed(200), Bg: RGB24(1, 2, 3), Attrs: AttrBold | AttrCurlyUnderline}},
+		{"1;2;22", Style{}},
+		{"38:2::1:2:3", Style{Fg: RGB24(1, 2, 3)}},
+		{"97;104", Style{Fg: Indexed(15), Bg: Indexed(12)}},
+	}
+	for _, tt := range tests {
+		if got := (Style{}).ApplySGR(tt.params); got != tt.want {
+			t.Errorf("ApplySGR(%q) = %+v, want %+v", tt.params, got, tt.want)
+		}
+	}
+}
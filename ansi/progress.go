@@ -0,0 +1,215 @@
+package ansi
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressBar displays progress of a long running operation on a LiveRegion
+// line.
+//
+// In determinate mode (total > 0), the bar shows percentage, amount of
+// processed data, throughput, and estimated time to completion. In
+// indeterminate mode (total <= 0), a spinner is displayed along with the
+// amount of processed data.
+//
+// ProgressBar implements io.Writer, so it can be used with io.TeeReader or
+// io.MultiWriter to track io.Copy operations. Methods are safe for concurrent
+// use.
+type ProgressBar struct {
+	// guarded by the region lock, the bar is rendered in the background
+	label    string
+	width    int
+	bytes    bool
+	barStyle Style
+
+	total   atomic.Int64
+	current atomic.Int64
+	start   time.Time
+	line    *LiveLine
+	owned   *LiveRegion // region created by NewProgressBar
+	unicode bool
+}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+var asciiSpinnerFrames = []string{"|", "/", "-", "\\"}
+
+// NewProgressBar creates a progress bar on its own live region, call Finish
+// when done.
+func NewProgressBar(s *OutputState, label string, total int64) *ProgressBar {
+	r := NewLiveRegion(s)
+	p := r.AddProgress(label, total)
+	p.owned = r
+	return p
+}
+
+// AddProgress appends a progress bar line to the region
+func (r *LiveRegion) AddProgress(label string, total int64) *ProgressBar {
+	p := &ProgressBar{
+		label:   label,
+		bytes:   true,
+		start:   time.Now(),
+		unicode: r.tty,
+	}
+	if r.state != nil && r.state.ColorDepth() > DepthNone {
+		p.barStyle = Style{Fg: Indexed(6)}
+	}
+	p.total.Store(total)
+	p.line = r.addRenderLine(p.render)
+	return p
+}
+
+func (p *ProgressBar) locked(f func()) {
+	p.line.region.mu.Lock()
+	f()
+	p.line.region.mu.Unlock()
+}
+
+// SetLabel changes the text displayed before the bar
+func (p *ProgressBar) SetLabel(label string) {
+	p.locked(func() { p.label = label })
+}
+
+// SetWidth changes the width of the bar in cells, defaults to 30
+func (p *ProgressBar) SetWidth(width int) {
+	p.locked(func() { p.width = width })
+}
+
+// SetBytes chooses whether amounts are displayed as byte sizes (default) or
+// plain numbers
+func (p *ProgressBar) SetBytes(bytes bool) {
+	p.locked(func() { p.bytes = bytes })
+}
+
+// SetBarStyle changes the style of the filled part of the bar
+func (p *ProgressBar) SetBarStyle(style Style) {
+	p.locked(func() { p.barStyle = style })
+}
+
+// SetTotal changes the total amount, use total <= 0 for indeterminate mode
+func (p *ProgressBar) SetTotal(total int64) {
+	p.total.Store(total)
+}
+
+// Set sets the current amount
+func (p *ProgressBar) Set(n int64) {
+	p.current.Store(n)
+}
+
+// Add increments the current amount
+func (p *ProgressBar) Add(n int64) {
+	p.current.Add(n)
+}
+
+// Write implements io.Writer by counting written bytes
+func (p *ProgressBar) Write(b []byte) (int, error) {
+	p.current.Add(int64(len(b)))
+	return len(b), nil
+}
+
+// Finish replaces the progress bar with a final message (an empty message
+// keeps the final state of the bar), stops the region created by
+// NewProgressBar.
+func (p *ProgressBar) Finish(message string) {
+	if message == "" {
+		p.line.finish(nil)
+	} else {
+		p.line.Done(message)
+	}
+	if p.owned != nil {
+		p.owned.Stop()
+	}
+}
+
+func (p *ProgressBar) amount(n int64) string {
+	if p.bytes {
+		return formatBytes(n)
+	}
+	return fmt.Sprint(n)
+}
+
+// render produces the progress bar text, width=0 is used for plain log lines,
+// called with the region lock held
+func (p *ProgressBar) render(width int) string {
+	total := p.total.Load()
+	current := p.current.Load()
+	elapsed := time.Since(p.start)
+
+	var b strings.Builder
+	if p.label != "" {
+		b.WriteString(p.label)
+		b.WriteString(" ")
+	}
+
+	if total <= 0 {
+		if width > 0 {
+			frames := asciiSpinnerFrames
+			if p.unicode {
+				frames = spinnerFrames
+			}
+			b.WriteString(frames[int(elapsed/RefreshInterval)%len(frames)])
+			b.WriteString(" ")
+		}
+		b.WriteString(p.amount(current))
+		if rate := throughput(current, elapsed); rate > 0 {
+			fmt.Fprintf(&b, " %s/s", p.amount(int64(rate)))
+		}
+		return b.String()
+	}
+
+	ratio := float64(current) / float64(total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	if width > 0 {
+		bw := p.width
+		if bw <= 0 {
+			bw = 30
+		}
+		filled := int(ratio * float64(bw))
+		full, empty := "#", "-"
+		if p.unicode {
+			full, empty = "█", "░"
+		}
+		b.WriteString(p.barStyle.Render(strings.Repeat(full, filled)))
+		b.WriteString(strings.Repeat(empty, bw-filled))
+		b.WriteString(" ")
+	}
+	fmt.Fprintf(&b, "%3.0f%% %s/%s", ratio*100, p.amount(current), p.amount(total))
+	if rate := throughput(current, elapsed); rate > 0 {
+		fmt.Fprintf(&b, " %s/s", p.amount(int64(rate)))
+		if current < total {
+			eta := time.Duration(float64(total-current) / rate * float64(time.Second))
+			fmt.Fprintf(&b, " ETA %s", eta.Round(time.Second))
+		}
+	}
+	return b.String()
+}
+
+func throughput(n int64, elapsed time.Duration) float64 {
+	if elapsed < time.Second/2 || n <= 0 {
+		return 0
+	}
+	return float64(n) / elapsed.Seconds()
+}
+
+var byteUnits = []string{"B", "KB", "MB", "GB", "TB", "PB", "EB"}
+
+// formatBytes formats a byte size with decimal units
+func formatBytes(n int64) string {
+	if n < 1000 {
+		return fmt.Sprintf("%dB", n)
+	}
+	e := int(math.Log(float64(n)) / math.Log(1000))
+	if e >= len(byteUnits) {
+		e = len(byteUnits) - 1
+	}
+	v := float64(n) / math.Pow(1000, float64(e))
+	if v < 10 {
+		return fmt.Sprintf("%.1f%s", v, byteUnits[e])
+	}
+	return fmt.Sprintf("%.0f%s", v, byteUnits[e])
+}
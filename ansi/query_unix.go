@@ -0,0 +1,58 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+// +build linux darwin freebsd openbsd netbsd dragonfly
+
+package ansi
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// implQueryBackground sends the background color query to the controlling
+// terminal and waits for the response
+func implQueryBackground(timeout time.Duration) (RGBColor, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return RGBColor{}, err
+	}
+	defer tty.Close()
+	fd := int(tty.Fd())
+
+	old, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return RGBColor{}, err
+	}
+	raw := *old
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Cc[unix.VMIN] = 0
+	raw.Cc[unix.VTIME] = 1 // reads time out after 100ms
+	if err = unix.IoctlSetTermios(fd, ioctlWriteTermios, &raw); err != nil {
+		return RGBColor{}, err
+	}
+	defer unix.IoctlSetTermios(fd, ioctlWriteTermios, old)
+
+	if _, err = tty.WriteString(backgroundQuery); err != nil {
+		return RGBColor{}, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	tty.SetReadDeadline(deadline)
+	var buf []byte
+	var tmp [64]byte
+	for time.Now().Before(deadline) && !isDeviceAttributesResponse(buf) {
+		n, err := tty.Read(tmp[:])
+		if n > 0 {
+			buf = append(buf, tmp[:n]...)
+		} else if err != nil && !os.IsTimeout(err) && !errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	if c, ok := parseBackgroundResponse(string(buf)); ok {
+		return c, nil
+	}
+	return RGBColor{}, errNoResponse
+}
@@ -0,0 +1,13 @@
+package ansi
+
+import (
+	"errors"
+	"time"
+)
+
+var errUnsupported = errors.New("unsupported")
+
+// implQueryBackground is not supported on windows consoles
+func implQueryBackground(timeout time.Duration) (RGBColor, error) {
+	return RGBColor{}, errUnsupported
+}
@@ -0,0 +1,110 @@
+package ansi
+
+import "strings"
+
+type seqKind int
+
+const (
+	seqInvalid = seqKind(iota) // malformed or interrupted sequence
+	seqCSI                     // control sequence: ESC [ params intermediates final
+	seqOSC                     // operating system command: ESC ] ... BEL|ST
+	seqString                  // DCS, SOS, PM, APC: ESC P|X|^|_ ... ST
+	seqEscape                  // other escape sequences: ESC intermediates final
+)
+
+const esc = 0x1b
+
+// scanSequence scans an escape sequence at the beginning of b (b[0] must be
+// ESC).
+//
+// Returns the length of the sequence and its kind, or n=0 if b contains an
+// incomplete sequence that may be continued with more data.
+func scanSequence[T ~string | ~[]byte](b T) (n int, kind seqKind) {
+	if len(b) < 2 {
+		return 0, seqInvalid
+	}
+	switch b[1] {
+	case '[':
+		for i := 2; i < len(b); i++ {
+			c := b[i]
+			switch {
+			case c >= 0x40 && c <= 0x7e:
+				return i + 1, seqCSI
+			case c < 0x20 || c > 0x7e:
+				return i, seqInvalid
+			}
+		}
+		return 0, seqCSI
+	case ']', 'P', 'X', '^', '_':
+		kind = seqString
+		if b[1] == ']' {
+			kind = seqOSC
+		}
+		for i := 2; i < len(b); i++ {
+			switch b[i] {
+			case 0x07:
+				if kind == seqOSC {
+					return i + 1, kind
+				}
+			case esc:
+				if i+1 == len(b) {
+					return 0, kind
+				}
+				if b[i+1] == '\\' {
+					return i + 2, kind
+				}
+				return i, seqInvalid
+			}
+		}
+		return 0, kind
+	}
+	for i := 1; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c >= 0x20 && c <= 0x2f:
+			// intermediate bytes
+		case c >= 0x30 && c <= 0x7e:
+			return i + 1, seqEscape
+		default:
+			return i, seqInvalid
+		}
+	}
+	return 0, seqEscape
+}
+
+// isSGR checks if a complete CSI sequence is a Select Graphic Rendition
+// sequence, which has the form ESC [ params m
+func isSGR[T ~string | ~[]byte](seq T) bool {
+	if len(seq) < 3 || seq[len(seq)-1] != 'm' {
+		return false
+	}
+	for i := 2; i < len(seq)-1; i++ {
+		c := seq[i]
+		if (c < '0' || c > '9') && c != ';' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// Strip removes all escape sequences from s
+func Strip(s string) string {
+	i := strings.IndexByte(s, esc)
+	if i < 0 {
+		return s
+	}
+	buf := make([]byte, 0, len(s))
+	for i >= 0 {
+		buf = append(buf, s[:i]...)
+		s = s[i:]
+		n, _ := scanSequence(s)
+		if n == 0 {
+			// incomplete sequence at the end
+			return string(buf)
+		}
+		s = s[n:]
+		i = strings.IndexByte(s, esc)
+	}
+	buf = append(buf, s...)
+	return string(buf)
+}
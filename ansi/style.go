@@ -1,8 +1,9 @@
 package ansi
 
-import "strconv"
-
-type Style int
+import (
+	"strconv"
+	"strings"
+)
 
 const (
 	Reset         = "\x1b[0m"
@@ -40,9 +41,243 @@ func ForegroundGray(l byte) string {
 func BackgroundGray(l byte) string {
 	return Background(Gray(l))
 }
+
+// ForegroundRGB snaps the color to the 256-color palette (see RGB), use
+// ForegroundTrueColor for terminals that support 24-bit colors.
 func ForegroundRGB(r, g, b byte) string {
 	return Foreground(RGB(r, g, b))
 }
+
+// BackgroundRGB snaps the color to the 256-color palette (see RGB), use
+// BackgroundTrueColor for terminals that support 24-bit colors.
 func BackgroundRGB(r, g, b byte) string {
 	return Background(RGB(r, g, b))
 }
+
+// ForegroundTrueColor produces a 24-bit foreground color sequence
+func ForegroundTrueColor(r, g, b byte) string {
+	return "\x1b[38;2;" + strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b)) + "m"
+}
+
+// BackgroundTrueColor produces a 24-bit background color sequence
+func BackgroundTrueColor(r, g, b byte) string {
+	return "\x1b[48;2;" + strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b)) + "m"
+}
+
+// ForegroundBase produces a foreground color sequence that uses one of the 16
+// base colors (SGR 30..37, 90..97), other palette entries are mapped to the
+// nearest base color.
+func ForegroundBase(v ColorIndex) string {
+	return "\x1b[" + baseParam(30, 90, v.Base()) + "m"
+}
+
+// BackgroundBase produces a background color sequence that uses one of the 16
+// base colors (SGR 40..47, 100..107), other palette entries are mapped to the
+// nearest base color.
+func BackgroundBase(v ColorIndex) string {
+	return "\x1b[" + baseParam(40, 100, v.Base()) + "m"
+}
+
+// Attr is a set of text attribute flags
+type Attr uint16
+
+const (
+	AttrBold = Attr(1 << iota)
+	AttrDim
+	AttrItalic
+	AttrUnderline
+	AttrDoubleUnderline
+	AttrCurlyUnderline
+	AttrDottedUnderline
+	AttrDashedUnderline
+	AttrBlinking
+	AttrInverse
+	AttrHidden
+	AttrStrikeThrough
+	AttrOverline
+
+	// AttrAnyUnderline combines all underline variants, only one of them is
+	// rendered at a time
+	AttrAnyUnderline = AttrUnderline | AttrDoubleUnderline | AttrCurlyUnderline |
+		AttrDottedUnderline | AttrDashedUnderline
+)
+
+// attrCodes lists SGR codes that set and reset the attributes, in the order
+// of emission
+var attrCodes = []struct {
+	attr  Attr
+	set   string
+	reset string
+}{
+	{AttrBold, "1", "22"},
+	{AttrDim, "2", "22"},
+	{AttrItalic, "3", "23"},
+	{AttrDoubleUnderline, "4:2", "24"},
+	{AttrCurlyUnderline, "4:3", "24"},
+	{AttrDottedUnderline, "4:4", "24"},
+	{AttrDashedUnderline, "4:5", "24"},
+	{AttrUnderline, "4", "24"},
+	{AttrBlinking, "5", "25"},
+	{AttrInverse, "7", "27"},
+	{AttrHidden, "8", "28"},
+	{AttrStrikeThrough, "9", "29"},
+	{AttrOverline, "53", "55"},
+}
+
+// Style combines foreground and background colors with text attributes. The
+// zero value is an empty style that renders text as-is.
+type Style struct {
+	Fg    Color
+	Bg    Color
+	Attrs Attr
+}
+
+// IsZero returns true if the style does not change text appearance
+func (s Style) IsZero() bool {
+	return s.Fg.IsDefault() && s.Bg.IsDefault() && s.Attrs == 0
+}
+
+// Merge returns a style with other applied on top of s: non-default colors
+// of other replace those of s, attributes are combined (an underline variant
+// in other replaces the underline variant of s).
+func (s Style) Merge(other Style) Style {
+	if !other.Fg.IsDefault() {
+		s.Fg = other.Fg
+	}
+	if !other.Bg.IsDefault() {
+		s.Bg = other.Bg
+	}
+	if other.Attrs&AttrAnyUnderline != 0 {
+		s.Attrs &^= AttrAnyUnderline
+	}
+	s.Attrs |= other.Attrs
+	return s
+}
+
+// Downgrade converts style colors to the best representation available with
+// the specified color depth (see Color.Downgrade).
+func (s Style) Downgrade(depth ColorDepth) Style {
+	s.Fg = s.Fg.Downgrade(depth)
+	s.Bg = s.Bg.Downgrade(depth)
+	return s
+}
+
+// Open returns a single SGR sequence that turns the style on, or an empty
+// string for a zero style.
+func (s Style) Open() string {
+	var params []string
+	underline := false
+	for _, c := range attrCodes {
+		if s.Attrs&c.attr == 0 {
+			continue
+		}
+		if c.attr&AttrAnyUnderline != 0 {
+			if underline {
+				continue
+			}
+			underline = true
+		}
+		params = append(params, c.set)
+	}
+	if !s.Fg.IsDefault() {
+		params = append(params, s.Fg.sgr(38))
+	}
+	if !s.Bg.IsDefault() {
+		params = append(params, s.Bg.sgr(48))
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// Close returns a single SGR sequence that resets only the attributes and
+// colors that the style turns on, or an empty string for a zero style.
+func (s Style) Close() string {
+	var params []string
+	for _, c := range attrCodes {
+		if s.Attrs&c.attr == 0 {
+			continue
+		}
+		dup := false
+		for _, p := range params {
+			if p == c.reset {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			params = append(params, c.reset)
+		}
+	}
+	if !s.Fg.IsDefault() {
+		params = append(params, "39")
+	}
+	if !s.Bg.IsDefault() {
+		params = append(params, "49")
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// Render wraps text with the style's Open and Close sequences.
+//
+// If text already contains SGR sequences that reset attributes or colors
+// (e.g. nested spans produced by Render), the style is re-applied after each
+// of them, so that the rest of the text is rendered correctly.
+func (s Style) Render(text string) string {
+	open := s.Open()
+	if open == "" {
+		return text
+	}
+	var b strings.Builder
+	b.Grow(len(open) + len(text) + 16)
+	b.WriteString(open)
+	for {
+		i := strings.IndexByte(text, esc)
+		if i < 0 {
+			break
+		}
+		n, kind := scanSequence(text[i:])
+		if n == 0 {
+			break
+		}
+		b.WriteString(text[:i+n])
+		seq := text[i : i+n]
+		text = text[i+n:]
+		if kind == seqCSI && isSGR(seq) && sgrResets(seq[2:len(seq)-1]) {
+			b.WriteString(open)
+		}
+	}
+	b.WriteString(text)
+	b.WriteString(s.Close())
+	return b.String()
+}
+
+// sgrResets checks if SGR parameters contain codes that reset attributes or
+// colors
+func sgrResets(params string) bool {
+	if params == "" {
+		return true
+	}
+	groups := strings.Split(params, ";")
+	for i := 0; i < len(groups); i++ {
+		p := groups[i]
+		if j := strings.IndexByte(p, ':'); j >= 0 {
+			if p == "4:0" {
+				return true
+			}
+			p = p[:j]
+		} else if p == "38" || p == "48" || p == "58" {
+			i += parseExtColor(groups[i+1:], false).consumed
+			continue
+		}
+		switch p {
+		case "", "0", "22", "23", "24", "25", "27", "28", "29", "39", "49", "55", "59":
+			return true
+		}
+	}
+	return false
+}
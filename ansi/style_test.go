@@ -0,0 +1,40 @@
+package ansi
+
+import "testing"
+
+func TestStyleRender(t *testing.T) {
+	bold := Style{Attrs: AttrBold}
+	dim := Style{Attrs: AttrDim}
+	red := Style{Fg: Indexed(1)}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"zero", Style{}.Render("x"), "x"},
+		{"bold", bold.Render("x"), "\x1b[1mx\x1b[22m"},
+		{"combined", Style{Fg: RGB24(1, 2, 3), Bg: Indexed(200), Attrs: AttrItalic | AttrCurlyUnderline}.Render("x"),
+			"\x1b[3;4:3;38;2;1;2;3;48;5;200mx\x1b[23;24;39;49m"},
+		{"bold-dim", Style{Attrs: AttrBold | AttrDim}.Render("x"), "\x1b[1;2mx\x1b[22m"},
+		{"nested", bold.Render("a" + dim.Render("b") + "c"),
+			"\x1b[1ma\x1b[2mb\x1b[22m\x1b[1mc\x1b[22m"},
+		{"nested-color", red.Render("a" + Style{Fg: RGB24(0, 0, 0)}.Render("b") + "c"),
+			"\x1b[31ma\x1b[38;2;0;0;0mb\x1b[39m\x1b[31mc\x1b[39m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStyleMerge(t *testing.T) {
+	a := Style{Fg: Indexed(1), Bg: Indexed(2), Attrs: AttrBold | AttrUnderline}
+	b := Style{Fg: Indexed(3), Attrs: AttrDoubleUnderline}
+	want := Style{Fg: Indexed(3), Bg: Indexed(2), Attrs: AttrBold | AttrDoubleUnderline}
+	if got := a.Merge(b); got != want {
+		t.Errorf("Merge() = %+v, want %+v", got, want)
+	}
+}
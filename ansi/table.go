@@ -0,0 +1,314 @@
+package ansi
+
+import (
+	"io"
+	"strings"
+)
+
+// BorderStyle selects the characters that are used for drawing table borders
+type BorderStyle int
+
+const (
+	BorderNone    = BorderStyle(iota) // columns are separated with spaces
+	BorderASCII                       // +-| characters
+	BorderUnicode                     // box drawing characters
+)
+
+type borderChars struct {
+	h, v       string
+	tl, tm, tr string
+	ml, mm, mr string
+	bl, bm, br string
+}
+
+var asciiBorder = borderChars{
+	h: "-", v: "|",
+	tl: "+", tm: "+", tr: "+",
+	ml: "+", mm: "+", mr: "+",
+	bl: "+", bm: "+", br: "+",
+}
+
+var unicodeBorder = borderChars{
+	h: "─", v: "│",
+	tl: "┌", tm: "┬", tr: "┐",
+	ml: "├", mm: "┼", mr: "┤",
+	bl: "└", bm: "┴", br: "┘",
+}
+
+// TableColumn configures a table column
+type TableColumn struct {
+	Header   string
+	Align    Align
+	Style    Style // applied to all cells in the column, cell styles are merged on top
+	MaxWidth int   // maximum content width in cells, 0 means unlimited
+	Wrap     bool  // wrap content that exceeds the column width instead of truncating
+}
+
+// TableCell is a table cell with an optional style
+type TableCell struct {
+	Text  string
+	Style Style
+}
+
+// Table renders rows of cells as aligned columns.
+//
+// When rendered to an output that is not a terminal, styles are dropped and
+// Unicode borders are replaced with ASCII, so that the table remains readable
+// in log files.
+type Table struct {
+	Columns     []TableColumn
+	Border      BorderStyle
+	BorderStyle Style // style of border characters
+	HeaderStyle Style // style of header cells, merged on top of column styles
+	MaxWidth    int   // total width limit, 0 uses terminal width (if available)
+
+	rows [][]TableCell
+}
+
+// NewTable creates a table with the specified column headers
+func NewTable(headers ...string) *Table {
+	t := &Table{
+		Border:      BorderUnicode,
+		HeaderStyle: Style{Attrs: AttrBold},
+	}
+	for _, h := range headers {
+		t.Columns = append(t.Columns, TableColumn{Header: h})
+	}
+	return t
+}
+
+// AddRow appends a row of unstyled cells
+func (t *Table) AddRow(cells ...string) {
+	row := make([]TableCell, len(cells))
+	for i, s := range cells {
+		row[i].Text = s
+	}
+	t.rows = append(t.rows, row)
+}
+
+// AddCells appends a row of (optionally) styled cells
+func (t *Table) AddCells(cells ...TableCell) {
+	t.rows = append(t.rows, append([]TableCell(nil), cells...))
+}
+
+// Rows returns the number of rows in the table, not including the header
+func (t *Table) Rows() int {
+	return len(t.rows)
+}
+
+// Print renders the table into w using capabilities of s, a nil s produces
+// plain text.
+func (t *Table) Print(w io.Writer, s *OutputState) error {
+	_, err := io.WriteString(w, t.Render(s))
+	return err
+}
+
+// Render renders the table using capabilities of s, a nil s produces plain
+// text.
+func (t *Table) Render(s *OutputState) string {
+	plain := s == nil || !s.Supported()
+	depth := DepthNone
+	if s != nil {
+		depth = s.ColorDepth()
+	}
+	nostyle := plain && depth == DepthNone
+	style := func(st Style) Style {
+		if nostyle {
+			return Style{}
+		}
+		return st.Downgrade(depth)
+	}
+	ellipsis := "…"
+	border := t.Border
+	if plain {
+		ellipsis = "..."
+		if border == BorderUnicode {
+			border = BorderASCII
+		}
+	}
+
+	ncols := len(t.Columns)
+	for _, row := range t.rows {
+		if len(row) > ncols {
+			ncols = len(row)
+		}
+	}
+	if ncols == 0 {
+		return ""
+	}
+	cols := make([]TableColumn, ncols)
+	copy(cols, t.Columns)
+	hasHeader := false
+	for _, c := range cols {
+		if c.Header != "" {
+			hasHeader = true
+			break
+		}
+	}
+
+	// natural column widths
+	widths := make([]int, ncols)
+	measure := func(i int, text string) {
+		for _, line := range strings.Split(text, "\n") {
+			if w := StringWidth(line); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	for i, c := range cols {
+		measure(i, c.Header)
+	}
+	for _, row := range t.rows {
+		for i, c := range row {
+			measure(i, c.Text)
+		}
+	}
+	for i, c := range cols {
+		if c.MaxWidth > 0 && widths[i] > c.MaxWidth {
+			widths[i] = c.MaxWidth
+		}
+	}
+
+	// fit into the total width by shrinking the widest columns
+	maxWidth := t.MaxWidth
+	if maxWidth == 0 && s != nil && s.IsTerminal() {
+		maxWidth, _, _ = s.Size()
+	}
+	if maxWidth > 0 {
+		overhead := 2 * (ncols - 1) // BorderNone: two spaces between columns
+		if border != BorderNone {
+			overhead = 3*ncols + 1
+		}
+		for total := overhead + sum(widths); total > maxWidth; total-- {
+			widest := 0
+			for i := range widths {
+				if widths[i] > widths[widest] {
+					widest = i
+				}
+			}
+			if widths[widest] <= 1 {
+				break
+			}
+			widths[widest]--
+		}
+	}
+
+	// formats a cell into one or more padded lines
+	format := func(i int, text string, st Style) []string {
+		var lines []string
+		if cols[i].Wrap {
+			lines = Wrap(text, widths[i])
+		} else {
+			lines = strings.Split(text, "\n")
+			for j, line := range lines {
+				lines[j] = Truncate(line, widths[i], ellipsis)
+			}
+		}
+		st = style(st)
+		if nostyle {
+			for j := range lines {
+				lines[j] = Strip(lines[j])
+			}
+		}
+		for j := range lines {
+			lines[j] = st.Render(Pad(lines[j], widths[i], cols[i].Align))
+		}
+		return lines
+	}
+
+	var b strings.Builder
+	var bc borderChars
+	switch border {
+	case BorderASCII:
+		bc = asciiBorder
+	case BorderUnicode:
+		bc = unicodeBorder
+	}
+	bs := style(t.BorderStyle)
+
+	rule := func(l, m, r string) {
+		if border == BorderNone {
+			return
+		}
+		var line strings.Builder
+		line.WriteString(l)
+		for i, w := range widths {
+			if i > 0 {
+				line.WriteString(m)
+			}
+			line.WriteString(strings.Repeat(bc.h, w+2))
+		}
+		line.WriteString(r)
+		b.WriteString(bs.Render(line.String()))
+		b.WriteByte('\n')
+	}
+
+	writeRow := func(cells [][]string) {
+		height := 0
+		for _, c := range cells {
+			if len(c) > height {
+				height = len(c)
+			}
+		}
+		for j := 0; j < height; j++ {
+			var line strings.Builder
+			if border != BorderNone {
+				line.WriteString(bs.Render(bc.v) + " ")
+			}
+			for i, c := range cells {
+				if i > 0 {
+					if border != BorderNone {
+						line.WriteString(" " + bs.Render(bc.v) + " ")
+					} else {
+						line.WriteString("  ")
+					}
+				}
+				if j < len(c) {
+					line.WriteString(c[j])
+				} else {
+					line.WriteString(strings.Repeat(" ", widths[i]))
+				}
+			}
+			if border != BorderNone {
+				line.WriteString(" " + bs.Render(bc.v))
+			}
+			s := line.String()
+			if border == BorderNone {
+				s = strings.TrimRight(s, " ")
+			}
+			b.WriteString(s)
+			b.WriteByte('\n')
+		}
+	}
+
+	rule(bc.tl, bc.tm, bc.tr)
+	if hasHeader {
+		cells := make([][]string, ncols)
+		for i, c := range cols {
+			cells[i] = format(i, c.Header, c.Style.Merge(t.HeaderStyle))
+		}
+		writeRow(cells)
+		rule(bc.ml, bc.mm, bc.mr)
+	}
+	for _, row := range t.rows {
+		cells := make([][]string, ncols)
+		for i := range cells {
+			var cell TableCell
+			if i < len(row) {
+				cell = row[i]
+			}
+			cells[i] = format(i, cell.Text, cols[i].Style.Merge(cell.Style))
+		}
+		writeRow(cells)
+	}
+	rule(bc.bl, bc.bm, bc.br)
+	return b.String()
+}
+
+func sum(v []int) int {
+	n := 0
+	for _, x := range v {
+		n += x
+	}
+	return n
+}
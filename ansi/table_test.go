@@ -0,0 +1,53 @@
+package ansi
+
+import "testing"
+
+func TestTableRender(t *testing.T) {
+	tbl := NewTable("target", "status", "size")
+	tbl.Columns[2].Align = AlignRight
+	tbl.AddRow("linux/amd64", "ok", "1.2MB")
+	tbl.AddCells(TableCell{Text: "windows/arm64"}, TableCell{Text: "failed", Style: Style{Fg: Indexed(1)}})
+
+	want := "" +
+		"+---------------+--------+-------+\n" +
+		"| target        | status |  size |\n" +
+		"+---------------+--------+-------+\n" +
+		"| linux/amd64   | ok     | 1.2MB |\n" +
+		"| windows/arm64 | failed |       |\n" +
+		"+---------------+--------+-------+\n"
+	if got := tbl.Render(nil); got != want {
+		t.Errorf("plain:\n%s\nwant:\n%s", got, want)
+	}
+
+	tbl.Border = BorderNone
+	tbl.MaxWidth = 24
+	want = "" +
+		"target     status   size\n" +
+		"linux/...  ok      1.2MB\n" +
+		"window...  failed\n"
+	if got := tbl.Render(nil); got != want {
+		t.Errorf("truncated:\n%s\nwant:\n%s", got, want)
+	}
+
+	tbl.Columns[0].Wrap = true
+	tbl.MaxWidth = 0
+	tbl.Columns[0].MaxWidth = 7
+	want = "" +
+		"target   status   size\n" +
+		"linux/a  ok      1.2MB\n" +
+		"md64\n" +
+		"windows  failed\n" +
+		"/arm64\n"
+	if got := tbl.Render(nil); got != want {
+		t.Errorf("wrapped:\n%s\nwant:\n%s", got, want)
+	}
+
+	s := &OutputState{supported: true, tty: true, depth: Depth256}
+	tbl = NewTable()
+	tbl.Border = BorderNone
+	tbl.AddCells(TableCell{Text: "a", Style: Style{Attrs: AttrBold}}, TableCell{Text: "b"})
+	want = "\x1b[1ma\x1b[22m  b\n"
+	if got := tbl.Render(s); got != want {
+		t.Errorf("styled: %q, want %q", got, want)
+	}
+}
@@ -1,24 +1,247 @@
 package ansi
 
-import "os"
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
 
 // OutputState allows restoring terminal to its original state
 type OutputState struct {
+	file        *os.File
 	supported   bool
+	tty         bool
+	depth       ColorDepth
+	hyperlinks  bool
 	restoreProc func()
+
+	mu             sync.Mutex
+	cursorHidden   bool
+	altScreen      bool
+	scrollRegion   bool
+	bracketedPaste bool
+	background     BackgroundTone
+
+	queryMu sync.Mutex // serializes terminal queries, not held with mu
 }
 
+// Supported indicates if the output supports virtual terminal escape sequences
 func (s *OutputState) Supported() bool {
 	return s.supported
 }
 
+// IsTerminal indicates if the output is attached to a terminal (console on
+// Windows hosts), this is false for regular files and pipes.
+func (s *OutputState) IsTerminal() bool {
+	return s.tty
+}
+
+// ColorDepth returns the detected number of colors the output is able to
+// display (see SetupOutput for detection rules).
+func (s *OutputState) ColorDepth() ColorDepth {
+	return s.depth
+}
+
+// Hyperlinks indicates if the output is likely to support OSC 8 hyperlinks.
+func (s *OutputState) Hyperlinks() bool {
+	return s.hyperlinks
+}
+
+// File returns the output file that was used in SetupOutput
+func (s *OutputState) File() *os.File {
+	return s.file
+}
+
+// Size returns the current terminal size in character cells.
+//
+// The size is queried on each call, so the result reflects terminal resizing.
+// If the output is not a terminal, falls back to COLUMNS and LINES environment
+// variables. Returns ok=false if the size can not be determined.
+func (s *OutputState) Size() (cols, rows int, ok bool) {
+	if s.tty && s.file != nil {
+		cols, rows, err := implTerminalSize(s.file)
+		if err == nil && cols > 0 && rows > 0 {
+			return cols, rows, true
+		}
+	}
+	cols, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	rows, _ = strconv.Atoi(os.Getenv("LINES"))
+	return cols, rows, cols > 0 && rows > 0
+}
+
+// Restore returns the output to its original state: shows the cursor, exits
+// the alternate screen, and resets the scroll region if these were changed
+// through the OutputState methods, then restores the original console mode.
+//
+// Restore can be called multiple times, use `defer s.Restore()` in main to
+// restore the output when the program panics, and RestoreOnInterrupt to
+// handle Ctrl+C.
 func (s *OutputState) Restore() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seq := ""
+	if s.scrollRegion {
+		seq += SaveCursor + ResetScrollRegion + RestoreCursor
+		s.scrollRegion = false
+	}
+	if s.altScreen {
+		seq += ExitAltScreen
+		s.altScreen = false
+	}
+	if s.bracketedPaste {
+		seq += DisableBracketedPaste
+		s.bracketedPaste = false
+	}
+	if s.cursorHidden {
+		seq += ShowCursor
+		s.cursorHidden = false
+	}
+	if seq != "" {
+		s.write(seq)
+	}
 	if s.restoreProc != nil {
 		s.restoreProc()
 		s.restoreProc = nil
 	}
 }
 
+// RestoreOnInterrupt installs a handler that restores the output and
+// terminates the program when it receives an interrupt (Ctrl+C) or
+// termination signal. The exit code is 128 + signal number. The handler is
+// shared with InputState.RestoreOnInterrupt, so that all the registered states
+// are restored before exiting.
+//
+// Call the returned stop function to uninstall the handler.
+func (s *OutputState) RestoreOnInterrupt() (stop func()) {
+	return onInterrupt(s.Restore)
+}
+
+// interrupts holds restore procedures that run when the program is
+// interrupted
+var interrupts struct {
+	mu       sync.Mutex
+	ch       chan os.Signal
+	handlers map[int]func()
+	next     int
+}
+
+func onInterrupt(restore func()) (stop func()) {
+	interrupts.mu.Lock()
+	defer interrupts.mu.Unlock()
+	if interrupts.ch == nil {
+		interrupts.ch = make(chan os.Signal, 1)
+		interrupts.handlers = map[int]func(){}
+		signal.Notify(interrupts.ch, os.Interrupt, syscall.SIGTERM)
+		go handleInterrupts(interrupts.ch)
+	}
+	id := interrupts.next
+	interrupts.next++
+	interrupts.handlers[id] = restore
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			interrupts.mu.Lock()
+			defer interrupts.mu.Unlock()
+			delete(interrupts.handlers, id)
+			if len(interrupts.handlers) == 0 && interrupts.ch != nil {
+				signal.Stop(interrupts.ch)
+				close(interrupts.ch)
+				interrupts.ch = nil
+			}
+		})
+	}
+}
+
+func handleInterrupts(ch chan os.Signal) {
+	sig, ok := <-ch
+	if !ok {
+		return
+	}
+	restoreAll()
+	code := 1
+	if n, ok := sig.(syscall.Signal); ok {
+		code = 128 + int(n)
+	}
+	os.Exit(code)
+}
+
+// restoreAll runs the registered restore procedures, most recent first
+func restoreAll() {
+	interrupts.mu.Lock()
+	defer interrupts.mu.Unlock()
+	ids := make([]int, 0, len(interrupts.handlers))
+	for id := range interrupts.handlers {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	for _, id := range ids {
+		interrupts.handlers[id]()
+	}
+}
+
+// write sends a control sequence to the output if it is supported
+func (s *OutputState) write(seq string) {
+	if s.supported && s.file != nil {
+		io.WriteString(s.file, seq)
+	}
+}
+
+// HideCursor hides the cursor, it will be shown again by Restore
+func (s *OutputState) HideCursor() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(HideCursor)
+	s.cursorHidden = s.supported
+}
+
+// ShowCursor shows the cursor
+func (s *OutputState) ShowCursor() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(ShowCursor)
+	s.cursorHidden = false
+}
+
+// EnterAltScreen switches to the alternate screen buffer, the main screen
+// will be restored by Restore
+func (s *OutputState) EnterAltScreen() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(EnterAltScreen)
+	s.altScreen = s.supported
+}
+
+// ExitAltScreen switches back to the main screen buffer
+func (s *OutputState) ExitAltScreen() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(ExitAltScreen)
+	s.altScreen = false
+}
+
+// SetScrollRegion limits scrolling to the lines from top to bottom (1-based,
+// inclusive), full screen scrolling will be restored by Restore
+func (s *OutputState) SetScrollRegion(top, bottom int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(ScrollRegion(top, bottom))
+	s.scrollRegion = s.supported
+}
+
+// ResetScrollRegion restores full screen scrolling
+func (s *OutputState) ResetScrollRegion() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(ResetScrollRegion)
+	s.scrollRegion = false
+}
+
 // SetupOutput prepares/validates an output to support virtual terminal escape sequences
 //
 // Windows hosts:
@@ -28,16 +251,120 @@ func (s *OutputState) Restore() {
 // Other hosts (linux, bsd):
 // - checks for termios support
 //
-// Returns:
-// - ok: indicates if the output supports virtual terminal escape sequences (will fail for regular files)
-// - cleanup: to be used for restoring the output to its original state
+// Color depth is detected from the environment, in order of precedence:
+//   - FORCE_COLOR: 0|false disables colors; 1|true, 2, 3 force 16, 256 and
+//     24-bit colors respectively, even if the output is not a terminal
+//   - NO_COLOR: disables colors when set to a non-empty value
+//   - outputs that are not terminals or have TERM=dumb get no colors
+//   - COLORTERM=truecolor|24bit, TERM=*-direct, and a few well-known terminal
+//     emulators enable 24-bit colors
+//   - TERM=*256color* enables 256 colors
+//   - other terminals get 16 colors (24-bit colors on Windows consoles with
+//     virtual terminal processing and no TERM variable)
 //
+// Hyperlink support is detected from TERM, TERM_PROGRAM and other emulator
+// specific variables, FORCE_HYPERLINK=0|1 overrides detection.
+//
+// Returns an OutputState that reports detected capabilities and can be used
+// for restoring the output to its original state.
 func SetupOutput(output *os.File) *OutputState {
-	ok, proc := implSetupOutput(output)
-	return &OutputState{supported: ok, restoreProc: proc}
+	tty, ok, proc := implSetupOutput(output)
+	return &OutputState{
+		file:        output,
+		supported:   ok,
+		tty:         tty,
+		depth:       detectColorDepth(os.LookupEnv, ok),
+		hyperlinks:  detectHyperlinks(os.LookupEnv, ok),
+		restoreProc: proc,
+	}
 }
 
 // SetupStdout configures os.Stdout to support virtual terminal escape sequences
 func SetupStdout() *OutputState {
 	return SetupOutput(os.Stdout)
 }
+
+type lookupEnvFunc = func(key string) (string, bool)
+
+func detectColorDepth(env lookupEnvFunc, supported bool) ColorDepth {
+	if v, set := env("FORCE_COLOR"); set {
+		switch strings.ToLower(v) {
+		case "0", "false", "none":
+			return DepthNone
+		case "", "1", "true":
+			return Depth16
+		case "2":
+			return Depth256
+		case "3":
+			return DepthTrueColor
+		}
+	}
+	if v, _ := env("NO_COLOR"); v != "" {
+		return DepthNone
+	}
+	if !supported {
+		return DepthNone
+	}
+
+	term, _ := env("TERM")
+	term = strings.ToLower(term)
+	if term == "dumb" {
+		return DepthNone
+	}
+	switch ct, _ := env("COLORTERM"); strings.ToLower(ct) {
+	case "truecolor", "24bit":
+		return DepthTrueColor
+	}
+	if strings.HasSuffix(term, "-direct") || strings.Contains(term, "truecolor") {
+		return DepthTrueColor
+	}
+	switch term {
+	case "xterm-kitty", "xterm-ghostty", "alacritty", "wezterm", "foot", "foot-extra":
+		return DepthTrueColor
+	}
+	switch tp, _ := env("TERM_PROGRAM"); tp {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty":
+		return DepthTrueColor
+	}
+	if wt, _ := env("WT_SESSION"); wt != "" {
+		return DepthTrueColor
+	}
+	if strings.Contains(term, "256color") {
+		return Depth256
+	}
+	if term == "" {
+		return platformColorDepth
+	}
+	return Depth16
+}
+
+func detectHyperlinks(env lookupEnvFunc, supported bool) bool {
+	if v, set := env("FORCE_HYPERLINK"); set {
+		return v != "0" && strings.ToLower(v) != "false"
+	}
+	if !supported {
+		return false
+	}
+
+	term, _ := env("TERM")
+	switch term {
+	case "dumb":
+		return false
+	case "xterm-kitty", "xterm-ghostty", "alacritty", "wezterm", "foot", "foot-extra":
+		return true
+	}
+	switch tp, _ := env("TERM_PROGRAM"); tp {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby":
+		return true
+	}
+	for _, k := range []string{"WT_SESSION", "KONSOLE_VERSION", "DOMTERM"} {
+		if v, _ := env(k); v != "" {
+			return true
+		}
+	}
+	if v, _ := env("VTE_VERSION"); v != "" {
+		n, err := strconv.Atoi(v)
+		return err == nil && n >= 5000
+	}
+	return false
+}
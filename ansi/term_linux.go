@@ -9,7 +9,22 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-func implSetupOutput(f *os.File) (ok bool, cleanup func()) {
-	_, err := unix.IoctlGetTermios(int(f.Fd()), unix.TCGETS)
-	return err == nil, func() {}
+const platformColorDepth = Depth16
+
+const (
+	ioctlReadTermios  = unix.TCGETS
+	ioctlWriteTermios = unix.TCSETS
+)
+
+func implSetupOutput(f *os.File) (tty bool, ok bool, cleanup func()) {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), ioctlReadTermios)
+	return err == nil, err == nil, func() {}
+}
+
+func implTerminalSize(f *os.File) (cols, rows int, err error) {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(ws.Col), int(ws.Row), nil
 }
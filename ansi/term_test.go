@@ -0,0 +1,91 @@
+package ansi
+
+import (
+	"os"
+	"testing"
+)
+
+func fakeEnv(vars map[string]string) lookupEnvFunc {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func TestDetectColorDepth(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		supported bool
+		want      ColorDepth
+	}{
+		{"pipe", map[string]string{"TERM": "xterm-256color"}, false, DepthNone},
+		{"dumb", map[string]string{"TERM": "dumb"}, true, DepthNone},
+		{"xterm", map[string]string{"TERM": "xterm"}, true, Depth16},
+		{"xterm-256color", map[string]string{"TERM": "xterm-256color"}, true, Depth256},
+		{"xterm-direct", map[string]string{"TERM": "xterm-direct"}, true, DepthTrueColor},
+		{"colorterm", map[string]string{"TERM": "xterm-256color", "COLORTERM": "truecolor"}, true, DepthTrueColor},
+		{"no-color", map[string]string{"TERM": "xterm-256color", "NO_COLOR": "1"}, true, DepthNone},
+		{"no-color-empty", map[string]string{"TERM": "xterm-256color", "NO_COLOR": ""}, true, Depth256},
+		{"force-color", map[string]string{"FORCE_COLOR": ""}, false, Depth16},
+		{"force-color-2", map[string]string{"FORCE_COLOR": "2"}, false, Depth256},
+		{"force-color-3", map[string]string{"FORCE_COLOR": "3", "NO_COLOR": "1"}, false, DepthTrueColor},
+		{"force-color-0", map[string]string{"FORCE_COLOR": "0", "TERM": "xterm-256color"}, true, DepthNone},
+		{"no-term", map[string]string{}, true, platformColorDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectColorDepth(fakeEnv(tt.env), tt.supported); got != tt.want {
+				t.Errorf("detectColorDepth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectHyperlinks(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		supported bool
+		want      bool
+	}{
+		{"pipe", map[string]string{"TERM_PROGRAM": "iTerm.app"}, false, false},
+		{"iterm", map[string]string{"TERM_PROGRAM": "iTerm.app"}, true, true},
+		{"vte-old", map[string]string{"VTE_VERSION": "4800"}, true, false},
+		{"vte", map[string]string{"VTE_VERSION": "6003"}, true, true},
+		{"xterm", map[string]string{"TERM": "xterm"}, true, false},
+		{"forced", map[string]string{"FORCE_HYPERLINK": "1"}, false, true},
+		{"disabled", map[string]string{"FORCE_HYPERLINK": "0", "WT_SESSION": "x"}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectHyperlinks(fakeEnv(tt.env), tt.supported); got != tt.want {
+				t.Errorf("detectHyperlinks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputStateRestore(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	restored := 0
+	s := &OutputState{file: f, supported: true, restoreProc: func() { restored++ }}
+	s.HideCursor()
+	s.EnterAltScreen()
+	s.Restore()
+	s.Restore()
+
+	want := HideCursor + EnterAltScreen + ExitAltScreen + ShowCursor
+	got, _ := os.ReadFile(f.Name())
+	if string(got) != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if restored != 1 {
+		t.Errorf("restoreProc called %d times, want 1", restored)
+	}
+}
@@ -12,12 +12,31 @@ var (
 	procSetConsoleMode               = kernel32.NewProc("SetConsoleMode")
 	procSetConsoleOutputCP           = kernel32.NewProc("SetConsoleOutputCP")
 	procGetConsoleOutputCP           = kernel32.NewProc("GetConsoleOutputCP")
+	procGetConsoleScreenBufferInfo   = kernel32.NewProc("GetConsoleScreenBufferInfo")
 	cEnableVirtualTerminalProcessing = uint32(0x4)
 	//cDisableNewlineAutoReturn        = uint32(0x0008)
 )
 
+const platformColorDepth = DepthTrueColor
+
 type mode = uint32
 
+type coord struct {
+	x, y int16
+}
+
+type smallRect struct {
+	left, top, right, bottom int16
+}
+
+type consoleScreenBufferInfo struct {
+	size              coord
+	cursorPosition    coord
+	attributes        uint16
+	window            smallRect
+	maximumWindowSize coord
+}
+
 func getMode(fd uintptr) (mode, error) {
 	var m mode
 	ok, _, e := procGetConsoleMode.Call(fd, uintptr(unsafe.Pointer(&m)))
@@ -50,36 +69,47 @@ func getOutputCP() (uint32, error) {
 	return uint32(cp), nil
 }
 
-func implSetupOutput(f *os.File) (ok bool, cleanup func()) {
+func implTerminalSize(f *os.File) (cols, rows int, err error) {
+	var info consoleScreenBufferInfo
+	ok, _, e := procGetConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return 0, 0, e
+	}
+	cols = int(info.window.right-info.window.left) + 1
+	rows = int(info.window.bottom-info.window.top) + 1
+	return cols, rows, nil
+}
+
+func implSetupOutput(f *os.File) (tty bool, ok bool, cleanup func()) {
 	fd := f.Fd()
 
 	mode, err := getMode(fd)
 	if err != nil {
-		return false, func() {}
+		return false, false, func() {}
 	}
 	codepage, err := getOutputCP()
 	if err != nil {
-		return false, func() {}
+		return true, false, func() {}
 	}
 	modeOk := mode&cEnableVirtualTerminalProcessing != 0 // already has VT enabled
 	codepageOk := codepage == 65001
 	if modeOk && codepageOk {
-		return true, func() {}
+		return true, true, func() {}
 	}
 
 	err = setMode(fd, mode|cEnableVirtualTerminalProcessing)
 	if err != nil {
-		return false, func() {}
+		return true, false, func() {}
 	}
 	err = setOutputCP(65001)
 	if err != nil {
 		// restore original immediately
 		setMode(fd, mode)
-		return false, func() {}
+		return true, false, func() {}
 	}
 
 	// return function that restores original console mode and output codepage
-	return true, func() {
+	return true, true, func() {
 		setOutputCP(codepage)
 		setMode(fd, mode)
 	}
@@ -0,0 +1,249 @@
+package ansi
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Role is a semantic purpose of styled text, resolved to a concrete style
+// by a Theme
+type Role int
+
+const (
+	RoleError = Role(iota)
+	RoleWarning
+	RoleSuccess
+	RoleMuted
+	RoleAccent
+	RolePath
+	RoleCode
+
+	roleCount
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleError:
+		return "error"
+	case RoleWarning:
+		return "warning"
+	case RoleSuccess:
+		return "success"
+	case RoleMuted:
+		return "muted"
+	case RoleAccent:
+		return "accent"
+	case RolePath:
+		return "path"
+	case RoleCode:
+		return "code"
+	default:
+		return "unknown"
+	}
+}
+
+// Theme maps semantic roles to styles
+type Theme struct {
+	Name   string
+	Styles [roleCount]Style
+}
+
+// Style returns the style for a role, or a zero style for unknown roles
+func (t *Theme) Style(r Role) Style {
+	if r < 0 || r >= roleCount {
+		return Style{}
+	}
+	return t.Styles[r]
+}
+
+// Render renders text with the style for a role
+func (t *Theme) Render(r Role, text string) string {
+	return t.Style(r).Render(text)
+}
+
+// DarkTheme is designed for terminals with dark backgrounds
+var DarkTheme = Theme{
+	Name: "dark",
+	Styles: [roleCount]Style{
+		RoleError:   {Fg: Indexed(203), Attrs: AttrBold},
+		RoleWarning: {Fg: Indexed(221)},
+		RoleSuccess: {Fg: Indexed(114)},
+		RoleMuted:   {Fg: Indexed(245)},
+		RoleAccent:  {Fg: Indexed(81), Attrs: AttrBold},
+		RolePath:    {Fg: Indexed(75)},
+		RoleCode:    {Fg: Indexed(180)},
+	},
+}
+
+// LightTheme is designed for terminals with light backgrounds
+var LightTheme = Theme{
+	Name: "light",
+	Styles: [roleCount]Style{
+		RoleError:   {Fg: Indexed(160), Attrs: AttrBold},
+		RoleWarning: {Fg: Indexed(130)},
+		RoleSuccess: {Fg: Indexed(28)},
+		RoleMuted:   {Fg: Indexed(242)},
+		RoleAccent:  {Fg: Indexed(31), Attrs: AttrBold},
+		RolePath:    {Fg: Indexed(25)},
+		RoleCode:    {Fg: Indexed(95)},
+	},
+}
+
+// BackgroundTone is a terminal background brightness
+type BackgroundTone int
+
+const (
+	BackgroundUnknown = BackgroundTone(iota)
+	BackgroundDark
+	BackgroundLight
+)
+
+// ThemeFor returns the built-in theme for a background, DarkTheme is used
+// when the background is unknown
+func ThemeFor(bg BackgroundTone) *Theme {
+	if bg == BackgroundLight {
+		return &LightTheme
+	}
+	return &DarkTheme
+}
+
+var errNoResponse = errors.New("no response from terminal")
+
+// queryBackground is replaced in tests
+var queryBackground = implQueryBackground
+
+// DetectBackground determines whether the terminal has a dark or light
+// background:
+//
+//   - from the COLORFGBG environment variable (set by rxvt, konsole, and
+//     others), if available
+//   - by querying the background color with OSC 11 if the output is a
+//     terminal, waiting at most timeout for the response; the query is
+//     skipped if timeout <= 0
+//
+// A detected result is cached, subsequent calls return the same value.
+func (s *OutputState) DetectBackground(timeout time.Duration) BackgroundTone {
+	if bg := s.cachedBackground(); bg != BackgroundUnknown {
+		return bg
+	}
+	if !s.supported || !s.tty || timeout <= 0 {
+		return BackgroundUnknown
+	}
+
+	// the query blocks, it is serialized separately to keep other methods
+	// responsive
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+	if bg := s.cachedBackground(); bg != BackgroundUnknown {
+		return bg
+	}
+	c, err := queryBackground(timeout)
+	if err != nil {
+		return BackgroundUnknown
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.background = backgroundFromRGB(c)
+	return s.background
+}
+
+// cachedBackground returns the previously detected background, or the one
+// specified with COLORFGBG
+func (s *OutputState) cachedBackground() BackgroundTone {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.background == BackgroundUnknown {
+		s.background = backgroundFromCOLORFGBG(os.Getenv("COLORFGBG"))
+	}
+	return s.background
+}
+
+// Theme returns the built-in theme that matches the detected terminal
+// background (see DetectBackground), with colors downgraded to the detected
+// color depth.
+func (s *OutputState) Theme(timeout time.Duration) *Theme {
+	t := *ThemeFor(s.DetectBackground(timeout))
+	for i := range t.Styles {
+		t.Styles[i] = t.Styles[i].Downgrade(s.ColorDepth())
+	}
+	return &t
+}
+
+// backgroundFromCOLORFGBG parses "fg;bg" or "fg;default;bg" values
+func backgroundFromCOLORFGBG(v string) BackgroundTone {
+	if v == "" {
+		return BackgroundUnknown
+	}
+	fields := strings.Split(v, ";")
+	n, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || n < 0 || n > 15 {
+		return BackgroundUnknown
+	}
+	if n == 7 || n >= 9 {
+		return BackgroundLight
+	}
+	return BackgroundDark
+}
+
+func backgroundFromRGB(c RGBColor) BackgroundTone {
+	// perceived brightness (ITU-R BT.601)
+	y := 299*int(c.R) + 587*int(c.G) + 114*int(c.B)
+	if y > 128*1000 {
+		return BackgroundLight
+	}
+	return BackgroundDark
+}
+
+// parseOSCColor parses color specifications in terminal responses:
+// rgb:r/g/b with 1 to 4 hex digits per channel
+func parseOSCColor(s string) (RGBColor, bool) {
+	s = strings.TrimPrefix(s, "rgba:")
+	s = strings.TrimPrefix(s, "rgb:")
+	parts := strings.Split(s, "/")
+	if len(parts) < 3 {
+		return RGBColor{}, false
+	}
+	var v [3]byte
+	for i := 0; i < 3; i++ {
+		p := parts[i]
+		if len(p) < 1 || len(p) > 4 {
+			return RGBColor{}, false
+		}
+		n, err := strconv.ParseUint(p, 16, 16)
+		if err != nil {
+			return RGBColor{}, false
+		}
+		scale := uint64(1)<<(4*len(p)) - 1
+		v[i] = byte((n*255 + scale/2) / scale)
+	}
+	return RGBColor{v[0], v[1], v[2]}, true
+}
+
+// parseBackgroundResponse extracts the background color from a terminal
+// response to the "\x1b]11;?" query
+func parseBackgroundResponse(resp string) (RGBColor, bool) {
+	for _, t := range Tokenize(resp) {
+		if t.Kind == TokenOSC && t.Command == 11 {
+			return parseOSCColor(t.Data)
+		}
+	}
+	return RGBColor{}, false
+}
+
+// backgroundQuery asks for the background color, followed by a primary
+// device attributes request that all terminals respond to, which tells when
+// to stop waiting if the terminal does not support OSC 11
+const backgroundQuery = "\x1b]11;?\x1b\\" + "\x1b[c"
+
+// isDeviceAttributesResponse checks if buf ends with a response to the
+// primary device attributes request: ESC [ ? ... c
+func isDeviceAttributesResponse(buf []byte) bool {
+	if len(buf) == 0 || buf[len(buf)-1] != 'c' {
+		return false
+	}
+	i := strings.LastIndex(string(buf), "\x1b[?")
+	return i >= 0
+}
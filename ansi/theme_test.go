@@ -0,0 +1,98 @@
+package ansi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackgroundFromCOLORFGBG(t *testing.T) {
+	tests := []struct {
+		v    string
+		want BackgroundTone
+	}{
+		{"", BackgroundUnknown},
+		{"15;0", BackgroundDark},
+		{"0;15", BackgroundLight},
+		{"0;default;7", BackgroundLight},
+		{"7;8", BackgroundDark},
+		{"15;default", BackgroundUnknown},
+	}
+	for _, tt := range tests {
+		if got := backgroundFromCOLORFGBG(tt.v); got != tt.want {
+			t.Errorf("backgroundFromCOLORFGBG(%q) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestParseBackgroundResponse(t *testing.T) {
+	tests := []struct {
+		resp string
+		want RGBColor
+		ok   bool
+	}{
+		{"\x1b]11;rgb:ffff/ffff/ffff\x1b\\\x1b[?62;c", RGBColor{255, 255, 255}, true},
+		{"\x1b]11;rgb:1e1e/2020/3030\x07\x1b[?1;2c", RGBColor{0x1e, 0x20, 0x30}, true},
+		{"\x1b]11;rgb:f/8/0\x07", RGBColor{255, 136, 0}, true},
+		{"\x1b[?1;2c", RGBColor{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseBackgroundResponse(tt.resp)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseBackgroundResponse(%q) = %v, %v, want %v, %v", tt.resp, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDetectBackgroundTimeout(t *testing.T) {
+	t.Setenv("COLORFGBG", "")
+	queries := 0
+	defer func() { queryBackground = implQueryBackground }()
+	queryBackground = func(time.Duration) (RGBColor, error) {
+		queries++
+		return RGBColor{0xff, 0xff, 0xff}, nil
+	}
+
+	s := &OutputState{supported: true, tty: true}
+	if bg := s.DetectBackground(0); bg != BackgroundUnknown || queries != 0 {
+		t.Errorf("zero timeout must not query the terminal: %v, %d queries", bg, queries)
+	}
+	if bg := s.DetectBackground(time.Millisecond); bg != BackgroundLight || queries != 1 {
+		t.Errorf("got %v, %d queries", bg, queries)
+	}
+	if bg := s.DetectBackground(time.Millisecond); bg != BackgroundLight || queries != 1 {
+		t.Errorf("result must be cached: %v, %d queries", bg, queries)
+	}
+}
+
+func TestDetectBackgroundUnlocked(t *testing.T) {
+	t.Setenv("COLORFGBG", "")
+	release := make(chan struct{})
+	started := make(chan struct{})
+	defer func() { queryBackground = implQueryBackground }()
+	queryBackground = func(time.Duration) (RGBColor, error) {
+		close(started)
+		<-release
+		return RGBColor{}, nil
+	}
+
+	s := &OutputState{supported: true, tty: true}
+	done := make(chan BackgroundTone)
+	go func() { done <- s.DetectBackground(time.Second) }()
+	<-started
+
+	// other methods must not wait for the query
+	locked := make(chan struct{})
+	go func() {
+		s.HideCursor()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Error("OutputState is locked while querying the terminal")
+	}
+	close(release)
+	if bg := <-done; bg != BackgroundDark {
+		t.Errorf("got %v, want dark", bg)
+	}
+}
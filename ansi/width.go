@@ -0,0 +1,271 @@
+package ansi
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Align specifies horizontal text alignment
+type Align int
+
+const (
+	AlignLeft = Align(iota)
+	AlignRight
+	AlignCenter
+)
+
+// wideRanges lists East Asian Wide and Fullwidth characters, as well as
+// characters with default emoji presentation; these occupy two terminal cells
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec},
+	{0x23f0, 0x23f0}, {0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267f, 0x267f}, {0x2693, 0x2693}, {0x26a1, 0x26a1},
+	{0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5}, {0x26ce, 0x26ce},
+	{0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27b0, 0x27b0}, {0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55}, {0x2e80, 0x303e},
+	{0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff}, {0xa000, 0xa4cf},
+	{0xa960, 0xa97f}, {0xac00, 0xd7a3}, {0xf900, 0xfaff}, {0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f}, {0xff00, 0xff60}, {0xffe0, 0xffe6}, {0x16fe0, 0x16fe4},
+	{0x17000, 0x18cff}, {0x1b000, 0x1b2ff}, {0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a}, {0x1f200, 0x1f251}, {0x1f300, 0x1f320},
+	{0x1f32d, 0x1f335}, {0x1f337, 0x1f37c}, {0x1f37e, 0x1f393}, {0x1f3a0, 0x1f3ca},
+	{0x1f3cf, 0x1f3d3}, {0x1f3e0, 0x1f3f0}, {0x1f3f4, 0x1f3f4}, {0x1f3f8, 0x1f43e},
+	{0x1f440, 0x1f440}, {0x1f442, 0x1f4fc}, {0x1f4ff, 0x1f53d}, {0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567}, {0x1f57a, 0x1f57a}, {0x1f595, 0x1f596}, {0x1f5a4, 0x1f5a4},
+	{0x1f5fb, 0x1f64f}, {0x1f680, 0x1f6c5}, {0x1f6cc, 0x1f6cc}, {0x1f6d0, 0x1f6d2},
+	{0x1f6d5, 0x1f6d7}, {0x1f6dc, 0x1f6df}, {0x1f6eb, 0x1f6ec}, {0x1f6f4, 0x1f6fc},
+	{0x1f7e0, 0x1f7eb}, {0x1f7f0, 0x1f7f0}, {0x1f90c, 0x1f93a}, {0x1f93c, 0x1f945},
+	{0x1f947, 0x1f9ff}, {0x1fa70, 0x1faff}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+const zwj = 0x200d
+
+// RuneWidth returns the number of terminal cells occupied by a rune:
+//
+//   - 0 for control characters, combining marks, and other zero-width
+//     characters
+//   - 2 for East Asian wide and fullwidth characters and emoji
+//   - 1 for everything else
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case r < 0x300:
+		return 1
+	case r >= 0x1160 && r <= 0x11ff, // hangul jungseong and jongseong
+		r >= 0xfe00 && r <= 0xfe0f, // variation selectors
+		r >= 0xe0100 && r <= 0xe01ef,
+		r >= 0x1f3fb && r <= 0x1f3ff, // emoji skin tone modifiers
+		unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), unicode.Is(unicode.Cf, r):
+		return 0
+	case r < 0x1100:
+		return 1
+	}
+	i := sort.Search(len(wideRanges), func(i int) bool { return wideRanges[i][1] >= r })
+	if i < len(wideRanges) && wideRanges[i][0] <= r {
+		return 2
+	}
+	return 1
+}
+
+// widthScanner tracks the state that is needed for measuring character
+// sequences that combine into a single glyph
+type widthScanner struct {
+	joined   bool // previous rune was zero width joiner
+	regional bool // previous rune was the first of a regional indicator pair
+}
+
+func (ws *widthScanner) width(r rune) int {
+	if ws.joined {
+		ws.joined = false
+		return 0
+	}
+	if r == zwj {
+		ws.joined = true
+		return 0
+	}
+	if r >= 0x1f1e6 && r <= 0x1f1ff { // regional indicators form flags in pairs
+		ws.regional = !ws.regional
+		if ws.regional {
+			return 2
+		}
+		return 0
+	}
+	ws.regional = false
+	return RuneWidth(r)
+}
+
+// StringWidth returns the number of terminal cells needed to display a string.
+// Escape sequences are ignored, wide characters count as two cells, zero width
+// joiner sequences and regional indicator pairs (flags) count as a single
+// glyph.
+func StringWidth(s string) int {
+	w := 0
+	ws := widthScanner{}
+	for i := 0; i < len(s); {
+		if s[i] == esc {
+			n, _ := scanSequence(s[i:])
+			if n == 0 {
+				break
+			}
+			i += n
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		w += ws.width(r)
+		i += n
+	}
+	return w
+}
+
+// Truncate shortens a string to fit within the specified number of cells,
+// replacing the removed part with tail (e.g. "…"). Escape sequences from the
+// removed part are kept, so that styles and hyperlinks remain balanced.
+func Truncate(s string, width int, tail string) string {
+	if StringWidth(s) <= width {
+		return s
+	}
+	tw := StringWidth(tail)
+	if tw > width {
+		tail, tw = "", 0
+	}
+	limit := width - tw
+
+	var b strings.Builder
+	b.Grow(len(s) + len(tail))
+	w := 0
+	cut := false
+	ws := widthScanner{}
+	for i := 0; i < len(s); {
+		if s[i] == esc {
+			n, _ := scanSequence(s[i:])
+			if n == 0 {
+				break
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		rw := ws.width(r)
+		if !cut && w+rw > limit {
+			cut = true
+			b.WriteString(tail)
+		}
+		if !cut {
+			b.WriteString(s[i : i+n])
+			w += rw
+		}
+		i += n
+	}
+	if !cut {
+		b.WriteString(tail)
+	}
+	return b.String()
+}
+
+// Pad aligns a string within the specified number of cells by adding spaces,
+// strings that are already wider are returned as-is.
+func Pad(s string, width int, align Align) string {
+	n := width - StringWidth(s)
+	if n <= 0 {
+		return s
+	}
+	switch align {
+	case AlignRight:
+		return strings.Repeat(" ", n) + s
+	case AlignCenter:
+		l := n / 2
+		return strings.Repeat(" ", l) + s + strings.Repeat(" ", n-l)
+	default:
+		return s + strings.Repeat(" ", n)
+	}
+}
+
+// PadLeft is a shortcut for Pad(s, width, AlignRight)
+func PadLeft(s string, width int) string {
+	return Pad(s, width, AlignRight)
+}
+
+// PadRight is a shortcut for Pad(s, width, AlignLeft)
+func PadRight(s string, width int) string {
+	return Pad(s, width, AlignLeft)
+}
+
+// Wrap breaks a string into lines that fit within the specified number of
+// cells. Lines are broken at spaces where possible, words that are wider than
+// the limit are broken at character boundaries. Explicit line breaks are
+// preserved. Escape sequences are kept as-is.
+func Wrap(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		lines = append(lines, wrapLine(para, width)...)
+	}
+	return lines
+}
+
+func wrapLine(s string, width int) []string {
+	var lines []string
+	var line, word strings.Builder
+	lineW, wordW := 0, 0
+	spaces := 0 // pending spaces between line and word
+
+	flushWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		if lineW > 0 && lineW+spaces+wordW > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineW, spaces = 0, 0
+		}
+		if lineW > 0 {
+			line.WriteString(strings.Repeat(" ", spaces))
+			lineW += spaces
+		}
+		line.WriteString(word.String())
+		lineW += wordW
+		word.Reset()
+		wordW, spaces = 0, 0
+	}
+
+	ws := widthScanner{}
+	for i := 0; i < len(s); {
+		if s[i] == esc {
+			n, _ := scanSequence(s[i:])
+			if n == 0 {
+				break
+			}
+			word.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		i += n
+		if r == ' ' {
+			flushWord()
+			spaces++
+			continue
+		}
+		rw := ws.width(r)
+		if wordW+rw > width && wordW > 0 {
+			// the word does not fit on any line, break it
+			flushWord()
+			lines = append(lines, line.String())
+			line.Reset()
+			lineW, spaces = 0, 0
+		}
+		word.WriteRune(r)
+		wordW += rw
+	}
+	flushWord()
+	return append(lines, line.String())
+}
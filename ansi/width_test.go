@@ -0,0 +1,68 @@
+package ansi
+
+import "testing"
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"\x1b[1;31mabc\x1b[0m", 3},
+		{"日本語", 6},
+		{"é", 1},    // combining acute accent
+		{"👍", 2},     // emoji
+		{"👍🏽", 2},    // skin tone modifier
+		{"👨‍👩‍👧", 2}, // zwj sequence
+		{"🇺🇸", 2},    // flag
+		{"\x1b]8;;http://x\x07link\x1b]8;;\x07", 4},
+	}
+	for _, tt := range tests {
+		if got := StringWidth(tt.s); got != tt.want {
+			t.Errorf("StringWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abc", 3, "abc"},
+		{"abcdef", 4, "abc…"},
+		{"日本語", 4, "日…"},
+		{"日本語", 5, "日本…"},
+		{"\x1b[1mabcdef\x1b[22m", 4, "\x1b[1mabc…\x1b[22m"},
+		{"ab\x1b[31mcdef\x1b[39m", 3, "ab\x1b[31m…\x1b[39m"},
+		{"abc", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.width, "…"); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		align Align
+		want  string
+	}{
+		{"ab", 5, AlignLeft, "ab   "},
+		{"ab", 5, AlignRight, "   ab"},
+		{"ab", 5, AlignCenter, " ab  "},
+		{"日本", 6, AlignCenter, " 日本 "},
+		{"\x1b[1mab\x1b[0m", 3, AlignLeft, "\x1b[1mab\x1b[0m "},
+		{"abcdef", 3, AlignLeft, "abcdef"},
+	}
+	for _, tt := range tests {
+		if got := Pad(tt.s, tt.width, tt.align); got != tt.want {
+			t.Errorf("Pad(%q, %d, %d) = %q, want %q", tt.s, tt.width, tt.align, got, tt.want)
+		}
+	}
+}
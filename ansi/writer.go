@@ -0,0 +1,269 @@
+package ansi
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Writer is an io.Writer wrapper that adapts escape sequences to the
+// capabilities of an output:
+//
+//   - on terminals, all sequences pass through, colors are downgraded to the
+//     detected color depth (24-bit -> 256 -> 16 -> none)
+//   - on files and pipes, all sequences are stripped, unless colors are forced
+//     with FORCE_COLOR, in which case only the (downgraded) SGR sequences are
+//     kept
+//
+// Sequences that are split across multiple Write calls are buffered until
+// complete, use Flush to write out an incomplete trailing sequence.
+type Writer struct {
+	w        io.Writer
+	depth    ColorDepth
+	controls bool // pass through non-SGR sequences
+	pending  []byte
+	buf      []byte
+}
+
+// maxPending limits buffering of unterminated sequences
+const maxPending = 4096
+
+// NewWriter creates a Writer that adapts escape sequences written to w
+// according to the capabilities detected in s. A nil s strips all sequences.
+func NewWriter(w io.Writer, s *OutputState) *Writer {
+	ret := &Writer{w: w}
+	if s != nil {
+		ret.depth = s.ColorDepth()
+		ret.controls = s.Supported()
+	}
+	return ret
+}
+
+// Writer creates a Writer for the output file that was used in SetupOutput
+func (s *OutputState) Writer() *Writer {
+	return NewWriter(s.file, s)
+}
+
+// ColorDepth returns the color depth the writer downgrades colors to
+func (w *Writer) ColorDepth() ColorDepth {
+	return w.depth
+}
+
+// Write implements io.Writer
+func (w *Writer) Write(p []byte) (int, error) {
+	data := p
+	if len(w.pending) > 0 {
+		data = append(w.pending, p...)
+		w.pending = nil
+	}
+	out := w.buf[:0]
+	for len(data) > 0 {
+		i := indexEsc(data)
+		if i < 0 {
+			out = append(out, data...)
+			break
+		}
+		out = append(out, data[:i]...)
+		data = data[i:]
+		n, kind := scanSequence(data)
+		if n == 0 {
+			if len(data) <= maxPending {
+				w.pending = append(w.pending[:0], data...)
+				break
+			}
+			// unterminated sequence, drop the ESC and treat the rest as text
+			n, kind = 1, seqInvalid
+		}
+		out = w.appendSequence(out, data[:n], kind)
+		data = data[n:]
+	}
+	w.buf = out
+	if len(out) > 0 {
+		if _, err := w.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// WriteString writes the contents of s
+func (w *Writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush writes out a buffered incomplete sequence (if pass-through is enabled)
+// and resets the buffer
+func (w *Writer) Flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	p := w.pending
+	w.pending = nil
+	if !w.controls {
+		return nil
+	}
+	_, err := w.w.Write(p)
+	return err
+}
+
+func (w *Writer) appendSequence(out []byte, seq []byte, kind seqKind) []byte {
+	if kind == seqCSI && isSGR(seq) {
+		if !w.controls && w.depth == DepthNone {
+			return out
+		}
+		params, ok := downgradeSGR(string(seq[2:len(seq)-1]), w.depth)
+		if !ok {
+			return out
+		}
+		out = append(out, "\x1b["...)
+		out = append(out, params...)
+		return append(out, 'm')
+	}
+	if w.controls && kind != seqInvalid {
+		return append(out, seq...)
+	}
+	return out
+}
+
+func indexEsc(b []byte) int {
+	for i, c := range b {
+		if c == esc {
+			return i
+		}
+	}
+	return -1
+}
+
+// downgradeSGR rewrites SGR parameters for the specified color depth. Returns
+// ok=false if nothing remains after removing unsupported parameters.
+func downgradeSGR(params string, depth ColorDepth) (ret string, ok bool) {
+	if params == "" {
+		return "", true // reset
+	}
+	groups := strings.Split(params, ";")
+	out := make([]string, 0, len(groups))
+	for i := 0; i < len(groups); i++ {
+		g := groups[i]
+		sub := strings.Split(g, ":")
+		code, err := strconv.Atoi(sub[0])
+		if sub[0] == "" {
+			code, err = 0, nil
+		}
+		if err != nil {
+			continue
+		}
+		switch {
+		case code == 38 || code == 48 || code == 58:
+			var c extColor
+			if len(sub) > 1 {
+				c = parseExtColor(sub[1:], true)
+			} else {
+				c = parseExtColor(groups[i+1:], false)
+				i += c.consumed
+			}
+			if c.valid {
+				if s := c.sgr(code, depth); s != "" {
+					out = append(out, s)
+				}
+			}
+		case (code >= 30 && code <= 37) || (code >= 90 && code <= 97) ||
+			(code >= 40 && code <= 47) || (code >= 100 && code <= 107) ||
+			code == 39 || code == 49 || code == 59:
+			if depth > DepthNone {
+				out = append(out, g)
+			}
+		default:
+			out = append(out, g)
+		}
+	}
+	if len(out) == 0 {
+		return "", false
+	}
+	return strings.Join(out, ";"), true
+}
+
+// extColor is a parsed extended color specification that follows 38, 48, or
+// 58 SGR codes: 5;n or 2;r;g;b (or colon-separated 5:n, 2:[cs]:r:g:b)
+type extColor struct {
+	valid    bool
+	indexed  bool
+	index    ColorIndex
+	rgb      RGBColor
+	consumed int
+}
+
+func parseExtColor(args []string, colons bool) (c extColor) {
+	if len(args) == 0 {
+		return
+	}
+	num := func(s string) (byte, bool) {
+		v, err := strconv.Atoi(s)
+		return byte(v), err == nil && v >= 0 && v <= 255
+	}
+	switch args[0] {
+	case "5":
+		if len(args) < 2 {
+			return
+		}
+		var v byte
+		v, c.valid = num(args[1])
+		c.index, c.indexed = ColorIndex(v), true
+		c.consumed = 2
+	case "2":
+		args = args[1:]
+		if colons && len(args) >= 4 {
+			args = args[1:] // skip colorspace id
+		}
+		if len(args) < 3 {
+			return
+		}
+		var okr, okg, okb bool
+		c.rgb.R, okr = num(args[0])
+		c.rgb.G, okg = num(args[1])
+		c.rgb.B, okb = num(args[2])
+		c.valid = okr && okg && okb
+		c.consumed = 4
+	}
+	return
+}
+
+func (c extColor) color() Color {
+	if c.indexed {
+		return Indexed(c.index)
+	}
+	return Color{kind: colorRGB, rgb: c.rgb}
+}
+
+func (c extColor) sgr(code int, depth ColorDepth) string {
+	switch depth {
+	case DepthTrueColor, Depth256:
+		if c.indexed {
+			return strconv.Itoa(code) + ";5;" + strconv.Itoa(int(c.index))
+		}
+		if depth == DepthTrueColor {
+			return strconv.Itoa(code) + ";2;" + strconv.Itoa(int(c.rgb.R)) + ";" +
+				strconv.Itoa(int(c.rgb.G)) + ";" + strconv.Itoa(int(c.rgb.B))
+		}
+		return strconv.Itoa(code) + ";5;" + strconv.Itoa(int(c.rgb.Index()))
+	case Depth16:
+		idx := c.index
+		if !c.indexed {
+			idx = c.rgb.Base()
+		}
+		idx = idx.Base()
+		switch code {
+		case 38:
+			return baseParam(30, 90, idx)
+		case 48:
+			return baseParam(40, 100, idx)
+		}
+	}
+	return ""
+}
+
+func baseParam(normal, bright int, v ColorIndex) string {
+	if v < 8 {
+		return strconv.Itoa(normal + int(v))
+	}
+	return strconv.Itoa(bright + int(v) - 8)
+}
@@ -0,0 +1,63 @@
+package ansi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	const input = "\x1b[1;38;2;255;128;0mbold\x1b[0m \x1b[2Kplain \x1b]8;;http://x\x07link\x1b]8;;\x07"
+	tests := []struct {
+		name  string
+		state *OutputState
+		want  string
+	}{
+		{"nil", nil,
+			"bold plain link"},
+		{"pipe", &OutputState{},
+			"bold plain link"},
+		{"forced", &OutputState{depth: Depth256},
+			"\x1b[1;38;5;208mbold\x1b[0m plain link"},
+		{"truecolor", &OutputState{supported: true, depth: DepthTrueColor},
+			input},
+		{"256", &OutputState{supported: true, depth: Depth256},
+			"\x1b[1;38;5;208mbold\x1b[0m \x1b[2Kplain \x1b]8;;http://x\x07link\x1b]8;;\x07"},
+		{"16", &OutputState{supported: true, depth: Depth16},
+			"\x1b[1;31mbold\x1b[0m \x1b[2Kplain \x1b]8;;http://x\x07link\x1b]8;;\x07"},
+		{"none", &OutputState{supported: true, depth: DepthNone},
+			"\x1b[1mbold\x1b[0m \x1b[2Kplain \x1b]8;;http://x\x07link\x1b]8;;\x07"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, tt.state)
+			// feed byte by byte to exercise buffering of split sequences
+			for i := 0; i < len(input); i++ {
+				w.Write([]byte{input[i]})
+			}
+			w.Flush()
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{"\x1b[31mred\x1b[0m", "red"},
+		{"a\x1b]0;title\x1b\\b", "ab"},
+		{"a\x1b7b\x1b8c", "abc"},
+		{"tail\x1b[3", "tail"},
+	}
+	for _, tt := range tests {
+		if got := Strip(tt.s); got != tt.want {
+			t.Errorf("Strip(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
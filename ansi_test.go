@@ -0,0 +1,109 @@
+package ansi
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adnsv/go-utils/ansi"
+)
+
+func TestTypesAreIdentical(t *testing.T) {
+	pairs := []struct {
+		name      string
+		root, pkg interface{}
+	}{
+		{"ColorIndex", ColorIndex(0), ansi.ColorIndex(0)},
+		{"Style", Style{}, ansi.Style{}},
+		{"OutputState", &OutputState{}, &ansi.OutputState{}},
+	}
+	for _, p := range pairs {
+		if reflect.TypeOf(p.root) != reflect.TypeOf(p.pkg) {
+			t.Errorf("%s: %v differs from %v", p.name, reflect.TypeOf(p.root), reflect.TypeOf(p.pkg))
+		}
+	}
+}
+
+func TestValuesMatch(t *testing.T) {
+	if RGB(1, 2, 3) != ansi.RGB(1, 2, 3) || Gray(100) != ansi.Gray(100) {
+		t.Error("color mapping differs")
+	}
+	if ForegroundRGB(1, 2, 3) != ansi.ForegroundRGB(1, 2, 3) || BackgroundGray(7) != ansi.BackgroundGray(7) {
+		t.Error("color sequences differ")
+	}
+	if Bold != ansi.Bold || Reset != ansi.Reset || Black != ansi.Black || White != ansi.White {
+		t.Error("constants differ")
+	}
+}
+
+// parseExported collects exported package-level declarations in dir
+func parseExported(t *testing.T, dir string) (names map[string]bool, files []*ast.File) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names = map[string]bool{}
+	add := func(id *ast.Ident) {
+		if id.IsExported() {
+			names[id.Name] = true
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			files = append(files, f)
+			for _, decl := range f.Decls {
+				switch d := decl.(type) {
+				case *ast.FuncDecl:
+					if d.Recv == nil {
+						add(d.Name)
+					}
+				case *ast.GenDecl:
+					for _, spec := range d.Specs {
+						switch s := spec.(type) {
+						case *ast.TypeSpec:
+							add(s.Name)
+						case *ast.ValueSpec:
+							for _, id := range s.Names {
+								add(id)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return names, files
+}
+
+// TestRootForwardsToSubpackage guards against the root package drifting
+// from the ansi subpackage: every root declaration must exist in the
+// subpackage, and root types must be aliases.
+func TestRootForwardsToSubpackage(t *testing.T) {
+	root, files := parseExported(t, ".")
+	sub, _ := parseExported(t, "ansi")
+	for name := range root {
+		if !sub[name] {
+			t.Errorf("%s is declared in the root package, but not in the ansi subpackage", name)
+		}
+	}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if ts := spec.(*ast.TypeSpec); !ts.Assign.IsValid() {
+					t.Errorf("type %s in the root package must be an alias", ts.Name.Name)
+				}
+			}
+		}
+	}
+}
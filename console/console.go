@@ -0,0 +1,216 @@
+// Package console implements a leveled logger for command line tools that
+// renders themed, colored output on terminals and plain text in files.
+package console
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adnsv/go-utils/ansi"
+)
+
+// Level is a message severity, values match slog levels
+type Level int
+
+const (
+	LevelDebug   = Level(-4)
+	LevelInfo    = Level(0)
+	LevelWarning = Level(4)
+	LevelError   = Level(8)
+)
+
+func (l Level) String() string {
+	switch {
+	case l < LevelInfo:
+		return "debug"
+	case l < LevelWarning:
+		return "info"
+	case l < LevelError:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// output is shared between a logger and its groups
+type output struct {
+	mu         sync.Mutex
+	w          *ansi.Writer
+	theme      *ansi.Theme
+	level      Level
+	timeFormat string
+}
+
+// Logger writes leveled messages:
+//
+//   - debug messages are muted and hidden by default (see Verbose)
+//   - warnings and errors get themed "warning:" and "error:" prefixes
+//   - nested steps are indented (see Group)
+//
+// Styles are adapted to the capabilities of the output with ansi.Writer. A
+// Logger is safe for concurrent use.
+type Logger struct {
+	out    *output
+	indent int
+}
+
+// BackgroundTimeout enables querying the terminal for its background color in
+// New and limits the time New waits for the response. The query is disabled
+// by default: it blocks, and it may consume the user's typeahead.
+var BackgroundTimeout time.Duration
+
+// New creates a logger that writes to w, using capabilities of s for styling
+// (a nil s produces plain text). The theme matches the terminal background
+// (see ansi.OutputState.Theme), which is only queried from the terminal if
+// BackgroundTimeout is set; use SetTheme to override.
+func New(w io.Writer, s *ansi.OutputState) *Logger {
+	var theme *ansi.Theme
+	if s != nil {
+		theme = s.Theme(BackgroundTimeout)
+	} else {
+		t := *ansi.ThemeFor(ansi.BackgroundUnknown)
+		for i := range t.Styles {
+			t.Styles[i] = t.Styles[i].Downgrade(ansi.DepthNone)
+		}
+		theme = &t
+	}
+	return &Logger{out: &output{
+		w:     ansi.NewWriter(w, s),
+		theme: theme,
+		level: LevelInfo,
+	}}
+}
+
+// SetTheme replaces the theme used for prefixes and muted text
+func (l *Logger) SetTheme(t *ansi.Theme) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.theme = t
+}
+
+// SetLevel sets the minimum level of messages that are displayed
+func (l *Logger) SetLevel(v Level) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.level = v
+}
+
+// Level returns the minimum level of messages that are displayed
+func (l *Logger) Level() Level {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	return l.out.level
+}
+
+// Quiet hides everything except errors
+func (l *Logger) Quiet() {
+	l.SetLevel(LevelError)
+}
+
+// Verbose displays all messages, including debug
+func (l *Logger) Verbose() {
+	l.SetLevel(LevelDebug)
+}
+
+// SetTimestamps enables timestamps in the specified time.Format layout, an
+// empty layout disables timestamps
+func (l *Logger) SetTimestamps(layout string) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.timeFormat = layout
+}
+
+// Enabled checks if messages of the specified level are displayed
+func (l *Logger) Enabled(v Level) bool {
+	return v >= l.Level()
+}
+
+// Group prints a title and returns a logger that indents its messages
+// under the title, use it for nested steps
+func (l *Logger) Group(format string, args ...interface{}) *Logger {
+	l.Log(LevelInfo, fmt.Sprintf(format, args...))
+	return &Logger{out: l.out, indent: l.indent + 1}
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.Log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.Log(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Warningf(format string, args ...interface{}) {
+	l.Log(LevelWarning, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.Log(LevelError, fmt.Sprintf(format, args...))
+}
+
+// Successf prints an info level message styled with the success role
+func (l *Logger) Successf(format string, args ...interface{}) {
+	l.log(time.Now(), LevelInfo, ansi.RoleSuccess, fmt.Sprintf(format, args...), "")
+}
+
+// Log prints a message with the specified level
+func (l *Logger) Log(v Level, msg string) {
+	l.log(time.Now(), v, -1, msg, "")
+}
+
+// log prints a message, role < 0 selects the style by level, attrs are
+// appended in a muted style
+func (l *Logger) log(t time.Time, v Level, role ansi.Role, msg string, attrs string) {
+	o := l.out
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if v < o.level {
+		return
+	}
+
+	var b strings.Builder
+	muted := o.theme.Style(ansi.RoleMuted)
+	if o.timeFormat != "" {
+		b.WriteString(muted.Render(t.Format(o.timeFormat)))
+		b.WriteByte(' ')
+	}
+	indent := strings.Repeat("  ", l.indent)
+
+	prefix := ""
+	switch {
+	case v >= LevelError:
+		prefix = o.theme.Render(ansi.RoleError, "error:") + " "
+	case v >= LevelWarning:
+		prefix = o.theme.Render(ansi.RoleWarning, "warning:") + " "
+	case v < LevelInfo:
+		role = ansi.RoleMuted
+	}
+
+	for i, line := range strings.Split(msg, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+			if o.timeFormat != "" {
+				b.WriteString(strings.Repeat(" ", len(t.Format(o.timeFormat))+1))
+			}
+		}
+		b.WriteString(indent)
+		if i == 0 {
+			b.WriteString(prefix)
+		} else if prefix != "" {
+			b.WriteString(strings.Repeat(" ", ansi.StringWidth(prefix)))
+		}
+		if role >= 0 {
+			line = o.theme.Render(role, line)
+		}
+		b.WriteString(line)
+	}
+	if attrs != "" {
+		b.WriteByte(' ')
+		b.WriteString(muted.Render(attrs))
+	}
+	b.WriteByte('\n')
+	o.w.WriteString(b.String())
+}
@@ -0,0 +1,70 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/adnsv/go-utils/ansi"
+	"golang.org/x/exp/slog"
+)
+
+func TestLoggerPlain(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, nil)
+	l.Debugf("hidden")
+	l.Infof("building %s", "app")
+	step := l.Group("step")
+	step.Warningf("line one\nline two")
+	step.Errorf("failed")
+	l.Quiet()
+	l.Infof("hidden")
+	l.Errorf("shown")
+
+	want := "" +
+		"building app\n" +
+		"step\n" +
+		"  warning: line one\n" +
+		"           line two\n" +
+		"  error: failed\n" +
+		"error: shown\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, nil)
+	log := slog.New(l.Handler()).With("pkg", "gen").WithGroup("req")
+	log.Debug("hidden")
+	log.Info("fetched", "url", "http://x", slog.Group("size", "n", 42))
+	log.Error("failed", "err", errors.New("no such file"))
+
+	want := "" +
+		"fetched pkg=gen req.url=http://x req.size.n=42\n" +
+		"error: failed pkg=gen req.err=\"no such file\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNewDetectsBackground(t *testing.T) {
+	t.Setenv("COLORFGBG", "0;15")
+	t.Setenv("FORCE_COLOR", "1")
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s := ansi.SetupOutput(f)
+	l := New(f, s)
+	if s.DetectBackground(0) != ansi.BackgroundLight {
+		t.Fatal("expected a light background")
+	}
+	if !reflect.DeepEqual(l.out.theme, s.Theme(0)) {
+		t.Error("logger theme does not match the detected background")
+	}
+}
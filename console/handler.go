@@ -0,0 +1,100 @@
+package console
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adnsv/go-utils/ansi"
+	"golang.org/x/exp/slog"
+)
+
+// Handler is a golang.org/x/exp/slog Handler that renders records through a Logger, so that
+// library code that uses slog produces the same output as the rest of a
+// command line tool. Attributes are appended to messages as key=value pairs
+// in a muted style. See StdHandler for the log/slog version.
+type Handler struct {
+	l      *Logger
+	attrs  string // preformatted attributes from WithAttrs
+	prefix string // group prefix from WithGroup
+}
+
+// Handler creates a slog.Handler that writes through the logger
+func (l *Logger) Handler() *Handler {
+	return &Handler{l: l}
+}
+
+// Enabled implements slog.Handler
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.l.Enabled(Level(level))
+}
+
+// Handle implements slog.Handler
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.prefix, a)
+		return true
+	})
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	h.l.log(t, Level(r.Level), -1, r.Message, strings.TrimPrefix(b.String(), " "))
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		appendAttr(&b, h.prefix, a)
+	}
+	return &Handler{l: h.l, attrs: b.String(), prefix: h.prefix}
+}
+
+// WithGroup implements slog.Handler
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{l: h.l, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+func appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendAttr(b, prefix, ga)
+		}
+		return
+	}
+	var s string
+	if a.Value.Kind() == slog.KindTime {
+		s = a.Value.Time().Format(time.RFC3339)
+	} else {
+		s = a.Value.String()
+	}
+	writeAttr(b, prefix+a.Key, s)
+}
+
+// writeAttr appends a key=value pair, quoting the value if needed
+func writeAttr(b *strings.Builder, key string, value string) {
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteByte('=')
+	value = ansi.Strip(value)
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(value)
+}
@@ -0,0 +1,85 @@
+//go:build go1.21
+
+package console
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// StdHandler is the log/slog version of Handler, available with Go 1.21 and
+// newer.
+type StdHandler struct {
+	l      *Logger
+	attrs  string // preformatted attributes from WithAttrs
+	prefix string // group prefix from WithGroup
+}
+
+// StdHandler creates a log/slog Handler that writes through the logger
+func (l *Logger) StdHandler() *StdHandler {
+	return &StdHandler{l: l}
+}
+
+// Enabled implements slog.Handler
+func (h *StdHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.l.Enabled(Level(level))
+}
+
+// Handle implements slog.Handler
+func (h *StdHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendStdAttr(&b, h.prefix, a)
+		return true
+	})
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	h.l.log(t, Level(r.Level), -1, r.Message, strings.TrimPrefix(b.String(), " "))
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (h *StdHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		appendStdAttr(&b, h.prefix, a)
+	}
+	return &StdHandler{l: h.l, attrs: b.String(), prefix: h.prefix}
+}
+
+// WithGroup implements slog.Handler
+func (h *StdHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &StdHandler{l: h.l, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+func appendStdAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendStdAttr(b, prefix, ga)
+		}
+		return
+	}
+	var s string
+	if a.Value.Kind() == slog.KindTime {
+		s = a.Value.Time().Format(time.RFC3339)
+	} else {
+		s = a.Value.String()
+	}
+	writeAttr(b, prefix+a.Key, s)
+}
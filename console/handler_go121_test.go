@@ -0,0 +1,26 @@
+//go:build go1.21
+
+package console
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestStdHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, nil)
+	log := slog.New(l.StdHandler()).With("pkg", "gen").WithGroup("req")
+	log.Debug("hidden")
+	log.Info("fetched", "url", "http://x", slog.Group("size", "n", 42))
+	log.Error("failed", "err", errors.New("no such file"))
+
+	want := "" +
+		"fetched pkg=gen req.url=http://x req.size.n=42\n" +
+		"error: failed pkg=gen req.err=\"no such file\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,370 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CopyOptions configures CopyTree and SyncTree.
+type CopyOptions struct {
+	// Filter is called for each source entry (and for each destination entry
+	// when pruning in SyncTree), returning false skips the entry; skipped
+	// directories are skipped with their content. Use entry.Rel to make
+	// decisions that are consistent for both sides.
+	Filter func(entry *WalkEntry) bool
+
+	OverwriteMatchingContent bool              // overwrite files even if content matches
+	DryRun                   bool              // report changes without touching the disk
+	OnFeedback               WriteFeedbackProc // use this if logging or user feedback is required
+}
+
+// CopyStats summarizes the changes made by CopyTree and SyncTree.
+type CopyStats struct {
+	Copied    int // files and symlinks that were created or overwritten
+	Updated   int // files with matching content that got their permissions changed
+	Unchanged int // files and symlinks that already had matching content
+	Removed   int // destination entries that were removed or replaced
+}
+
+type copier struct {
+	opts      *CopyOptions
+	stats     CopyStats
+	dir_modes []dirMode
+	seen      map[string]bool
+}
+
+type dirMode struct {
+	path string
+	perm fs.FileMode
+}
+
+// CopyTree copies the content of the src directory into dst, preserving file
+// modes and copying symlinks as symlinks. Files with matching content are
+// skipped (or only get their permissions updated), others are streamed into
+// place atomically. A destination directory located where the source has a
+// file or a symlink is an error (SyncTree replaces it). In dry-run mode, the
+// changes are reported with FeedbackDryRunWrite and FeedbackDryRunRemove.
+func CopyTree(src, dst string, opts *CopyOptions) (CopyStats, error) {
+	c := copier{opts: opts}
+	if c.opts == nil {
+		c.opts = &CopyOptions{}
+	}
+	err := checkTreePaths(src, dst, false)
+	if err == nil {
+		err = c.copyTree(src, dst)
+	}
+	if e := c.applyDirModes(); err == nil {
+		err = e
+	}
+	return c.stats, err
+}
+
+// SyncTree mirrors the content of the src directory into dst: same as
+// CopyTree, then removes destination entries that have no counterpart in src.
+// Destination entries rejected by the filter are kept. The source may not be
+// located inside the destination.
+func SyncTree(src, dst string, opts *CopyOptions) (CopyStats, error) {
+	c := copier{opts: opts, seen: map[string]bool{}}
+	if c.opts == nil {
+		c.opts = &CopyOptions{}
+	}
+	err := checkTreePaths(src, dst, true)
+	if err == nil {
+		err = c.copyTree(src, dst)
+	}
+	if err == nil {
+		err = c.prune(dst)
+	}
+	if e := c.applyDirModes(); err == nil {
+		err = e
+	}
+	return c.stats, err
+}
+
+func (c *copier) feedback(fb WriteFeedback, fn string) {
+	if c.opts.OnFeedback != nil {
+		c.opts.OnFeedback(fb, fn)
+	}
+}
+
+// walkError lets broken symlinks through, so that they can be copied as is.
+func walkError(entry *WalkEntry, err error) error {
+	if entry.Info != nil && entry.IsSymlink() && !errors.Is(err, ErrSymlinkCycle) {
+		return nil
+	}
+	return err
+}
+
+// realPath returns the absolute path with symlinks resolved, the trailing
+// components that do not exist yet are kept as is
+func realPath(fn string) (string, error) {
+	fn, err := filepath.Abs(fn)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for {
+		if real, err := filepath.EvalSymlinks(fn); err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		parent := filepath.Dir(fn)
+		if parent == fn {
+			return filepath.Join(fn, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(fn), rest)
+		fn = parent
+	}
+}
+
+// isInside checks if fn is the same as, or located inside, the dir
+func isInside(fn, dir string) bool {
+	rel, err := filepath.Rel(dir, fn)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// checkPaths rejects overlapping source and destination
+func checkTreePaths(src, dst string, sync bool) error {
+	real_src, err := realPath(src)
+	if err != nil {
+		return err
+	}
+	real_dst, err := realPath(dst)
+	if err != nil {
+		return err
+	}
+	if isInside(real_dst, real_src) {
+		return ErrDestinationInsideSource
+	}
+	if sync && isInside(real_src, real_dst) {
+		return ErrSourceInsideDestination
+	}
+	return nil
+}
+
+func (c *copier) copyTree(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return ErrFileNotDir
+	}
+	if err = c.copyDir(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	err = Walk(src, &WalkOptions{Dirs: true}, func(entry *WalkEntry, err error) error {
+		if err != nil {
+			if err = walkError(entry, err); err != nil {
+				return err
+			}
+		}
+		mode := entry.Info.Mode()
+		if c.opts.Filter != nil && !c.opts.Filter(entry) {
+			if mode.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if c.seen != nil {
+			c.seen[entry.Rel] = true
+		}
+		target := filepath.Join(dst, filepath.FromSlash(entry.Rel))
+		switch {
+		case mode.IsDir():
+			return c.copyDir(target, mode.Perm())
+		case mode&fs.ModeSymlink != 0:
+			return c.copySymlink(entry.Path, target)
+		case mode.IsRegular():
+			return c.copyFile(entry.Path, target, mode.Perm())
+		default:
+			return nil // devices, pipes and sockets are not copied
+		}
+	})
+	return err
+}
+
+// applyDirModes sets directory permissions, this is done last (including
+// after errors), so that read-only directories can be populated and pruned.
+func (c *copier) applyDirModes() error {
+	if c.opts.DryRun {
+		return nil
+	}
+	var first_err error
+	for i := len(c.dir_modes) - 1; i >= 0; i-- {
+		if err := os.Chmod(c.dir_modes[i].path, c.dir_modes[i].perm); err != nil && first_err == nil {
+			first_err = err
+		}
+	}
+	return first_err
+}
+
+func (c *copier) remove(fn string) error {
+	c.stats.Removed++
+	if c.opts.DryRun {
+		c.feedback(FeedbackDryRunRemove, fn)
+		return nil
+	}
+	c.feedback(FeedbackRemoveBegin, fn)
+	if err := os.RemoveAll(fn); err != nil {
+		c.feedback(FeedbackRemoveFailed, fn)
+		return err
+	}
+	c.feedback(FeedbackRemoveSucceded, fn)
+	return nil
+}
+
+// replace removes an existing destination entry that is in the way of a file
+// or a symlink, directories are only removed by SyncTree.
+func (c *copier) replace(target string, info fs.FileInfo) error {
+	if info.IsDir() && c.seen == nil {
+		return &fs.PathError{Op: "copy", Path: target, Err: ErrDirNotFile}
+	}
+	return c.remove(target)
+}
+
+func (c *copier) copyDir(target string, perm fs.FileMode) error {
+	info, err := os.Lstat(target)
+	if err == nil && info.IsDir() {
+		current := info.Mode().Perm()
+		if current != perm || current&0200 == 0 {
+			c.dir_modes = append(c.dir_modes, dirMode{target, perm})
+		}
+		if current&0200 == 0 && !c.opts.DryRun {
+			// writable while copying
+			return os.Chmod(target, current|0700)
+		}
+		return nil
+	}
+	if err == nil {
+		if err = c.remove(target); err != nil {
+			return err
+		}
+	}
+	if c.opts.DryRun {
+		return nil
+	}
+	if err = os.MkdirAll(target, 0777); err != nil {
+		return err
+	}
+	c.dir_modes = append(c.dir_modes, dirMode{target, perm})
+	return nil
+}
+
+func (c *copier) copyFile(src, target string, perm fs.FileMode) error {
+	src_info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(target)
+	if err == nil && !info.Mode().IsRegular() {
+		if err = c.replace(target, info); err != nil {
+			return err
+		}
+		info = nil
+	}
+	if info != nil && !c.opts.OverwriteMatchingContent && info.Size() == src_info.Size() {
+		match, err := filesMatch(src, target)
+		if err != nil {
+			return err
+		}
+		if match {
+			if info.Mode().Perm() == perm {
+				c.stats.Unchanged++
+				c.feedback(FeedbackWriteSkipped, target)
+				return nil
+			}
+			c.stats.Updated++
+			if c.opts.DryRun {
+				c.feedback(FeedbackDryRunWrite, target)
+				return nil
+			}
+			c.feedback(FeedbackWriteBegin, target)
+			if err = os.Chmod(target, perm); err != nil {
+				c.feedback(FeedbackWriteFailed, target)
+				return err
+			}
+			c.feedback(FeedbackWriteSucceded, target)
+			return nil
+		}
+	}
+
+	c.stats.Copied++
+	if c.opts.DryRun {
+		c.feedback(FeedbackDryRunWrite, target)
+		return nil
+	}
+	c.feedback(FeedbackWriteBegin, target)
+	if err = copyFileAtomic(src, target, perm); err != nil {
+		c.feedback(FeedbackWriteFailed, target)
+		return err
+	}
+	c.feedback(FeedbackWriteSucceded, target)
+	return nil
+}
+
+func (c *copier) copySymlink(src, target string) error {
+	link, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Lstat(target); err == nil {
+		if info.Mode()&fs.ModeSymlink != 0 && !c.opts.OverwriteMatchingContent {
+			if current, err := os.Readlink(target); err == nil && current == link {
+				c.stats.Unchanged++
+				c.feedback(FeedbackWriteSkipped, target)
+				return nil
+			}
+		}
+		if err = c.replace(target, info); err != nil {
+			return err
+		}
+	}
+
+	c.stats.Copied++
+	if c.opts.DryRun {
+		c.feedback(FeedbackDryRunWrite, target)
+		return nil
+	}
+	c.feedback(FeedbackWriteBegin, target)
+	if err = os.Symlink(link, target); err != nil {
+		c.feedback(FeedbackWriteFailed, target)
+		return err
+	}
+	c.feedback(FeedbackWriteSucceded, target)
+	return nil
+}
+
+// prune removes destination entries that were not copied from the source.
+func (c *copier) prune(dst string) error {
+	if !DirExists(dst) {
+		return nil
+	}
+	return Walk(dst, &WalkOptions{Dirs: true}, func(entry *WalkEntry, err error) error {
+		if err != nil {
+			if err = walkError(entry, err); err != nil {
+				return err
+			}
+		}
+		if c.seen[entry.Rel] {
+			return nil
+		}
+		is_dir := entry.Info.IsDir()
+		if c.opts.Filter != nil && !c.opts.Filter(entry) {
+			if is_dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err = c.remove(entry.Path); err != nil {
+			return err
+		}
+		if is_dir {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
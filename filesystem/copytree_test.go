@@ -0,0 +1,207 @@
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCopyAndSyncTree(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "out")
+	os.MkdirAll(filepath.Join(src, "sub"), 0777)
+	os.MkdirAll(filepath.Join(src, "skip"), 0777)
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0666)
+	os.WriteFile(filepath.Join(src, "run.sh"), []byte("#!/bin/sh"), 0755)
+	os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("b"), 0666)
+	os.WriteFile(filepath.Join(src, "skip", "c.txt"), []byte("c"), 0666)
+	symlinks := runtime.GOOS != "windows"
+	if symlinks {
+		os.Symlink("a.txt", filepath.Join(src, "link"))
+	}
+
+	opts := &CopyOptions{
+		Filter: func(entry *WalkEntry) bool { return entry.Rel != "skip" && entry.Rel != "keep.txt" },
+	}
+	stats, err := CopyTree(src, dst, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want_copied := 3
+	if symlinks {
+		want_copied++
+	}
+	if stats.Copied != want_copied || stats.Unchanged != 0 {
+		t.Errorf("first copy: %+v", stats)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dst, "sub", "b.txt")); string(b) != "b" {
+		t.Errorf("sub/b.txt: %q", b)
+	}
+	if DirExists(filepath.Join(dst, "skip")) {
+		t.Error("filtered directory was copied")
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(filepath.Join(dst, "run.sh")); err != nil || info.Mode().Perm() != 0755 {
+			t.Errorf("mode was not preserved: %v", info.Mode())
+		}
+	}
+	if symlinks {
+		if link, err := os.Readlink(filepath.Join(dst, "link")); err != nil || link != "a.txt" {
+			t.Errorf("symlink: %q, %v", link, err)
+		}
+	}
+
+	os.WriteFile(filepath.Join(dst, "extra.txt"), nil, 0666)
+	os.WriteFile(filepath.Join(dst, "keep.txt"), nil, 0666)
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("A"), 0666)
+
+	var dry []WriteFeedback
+	opts.DryRun = true
+	opts.OnFeedback = func(fb WriteFeedback, fn string) {
+		if fb == FeedbackDryRunWrite || fb == FeedbackDryRunRemove {
+			dry = append(dry, fb)
+		}
+	}
+	stats, err = SyncTree(src, dst, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Copied != 1 || stats.Removed != 1 || len(dry) != 2 {
+		t.Errorf("dry run: %+v, feedback %v", stats, dry)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dst, "a.txt")); string(b) != "a" || !FileExists(filepath.Join(dst, "extra.txt")) {
+		t.Error("dry run must not touch the disk")
+	}
+
+	opts.DryRun = false
+	opts.OnFeedback = nil
+	if _, err = SyncTree(src, dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dst, "a.txt")); string(b) != "A" {
+		t.Errorf("a.txt: %q", b)
+	}
+	if FileExists(filepath.Join(dst, "extra.txt")) || !FileExists(filepath.Join(dst, "keep.txt")) {
+		t.Error("extraneous files were not pruned, or filtered ones were")
+	}
+
+	if _, err = CopyTree(src, filepath.Join(src, "sub"), nil); !errors.Is(err, ErrDestinationInsideSource) {
+		t.Errorf("destination inside the source: %v", err)
+	}
+	if _, err = SyncTree(filepath.Join(src, "sub"), src, nil); !errors.Is(err, ErrSourceInsideDestination) {
+		t.Errorf("source inside the destination: %v", err)
+	}
+	if symlinks {
+		alias := filepath.Join(t.TempDir(), "alias")
+		os.Symlink(src, alias)
+		if _, err = CopyTree(src, filepath.Join(alias, "out"), nil); !errors.Is(err, ErrDestinationInsideSource) {
+			t.Errorf("destination inside the source through a symlink: %v", err)
+		}
+	}
+}
+
+func TestCopyTreeChanges(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	os.WriteFile(filepath.Join(src, "same.txt"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(dst, "same.txt"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(src, "size.txt"), []byte("longer"), 0644)
+	os.WriteFile(filepath.Join(dst, "size.txt"), []byte("short"), 0644)
+	big := bytes.Repeat([]byte("0123456789"), 10000)
+	os.WriteFile(filepath.Join(src, "big.bin"), big, 0644)
+	big[len(big)-1] = 'x'
+	os.WriteFile(filepath.Join(dst, "big.bin"), big, 0644)
+
+	want := CopyStats{Copied: 2, Unchanged: 1}
+	if runtime.GOOS != "windows" {
+		os.WriteFile(filepath.Join(src, "mode.sh"), []byte("#!/bin/sh"), 0755)
+		os.WriteFile(filepath.Join(dst, "mode.sh"), []byte("#!/bin/sh"), 0644)
+		os.Chmod(filepath.Join(dst, "mode.sh"), 0644)
+		want.Updated = 1
+	}
+
+	skipped := map[string]bool{}
+	opts := &CopyOptions{OnFeedback: func(fb WriteFeedback, fn string) {
+		if fb == FeedbackWriteSkipped {
+			skipped[filepath.Base(fn)] = true
+		}
+	}}
+	stats, err := CopyTree(src, dst, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != want {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+	if len(skipped) != 1 || !skipped["same.txt"] {
+		t.Errorf("skipped: %v", skipped)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "big.bin")); !bytes.Equal(got, bytes.Repeat([]byte("0123456789"), 10000)) {
+		t.Error("big.bin was not copied")
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(filepath.Join(dst, "mode.sh")); err != nil || info.Mode().Perm() != 0755 {
+			t.Errorf("mode was not updated: %v", info.Mode())
+		}
+	}
+}
+
+func TestSyncTreeReadOnlyDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directory permissions are not enforced on windows")
+	}
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "out")
+	os.MkdirAll(filepath.Join(src, "ro"), 0777)
+	os.WriteFile(filepath.Join(src, "ro", "a.txt"), []byte("a"), 0666)
+	os.Chmod(filepath.Join(src, "ro"), 0555)
+	defer os.Chmod(filepath.Join(src, "ro"), 0755)
+	if _, err := SyncTree(src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Join(dst, "ro"), 0755)
+
+	os.Chmod(filepath.Join(src, "ro"), 0755)
+	os.WriteFile(filepath.Join(src, "ro", "a.txt"), []byte("A"), 0666)
+	os.Chmod(filepath.Join(src, "ro"), 0555)
+	os.Chmod(filepath.Join(dst, "ro"), 0755)
+	os.WriteFile(filepath.Join(dst, "ro", "extra.txt"), nil, 0666)
+	os.Chmod(filepath.Join(dst, "ro"), 0555)
+
+	if _, err := SyncTree(src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dst, "ro", "a.txt")); string(b) != "A" {
+		t.Errorf("a.txt: %q", b)
+	}
+	if FileExists(filepath.Join(dst, "ro", "extra.txt")) {
+		t.Error("extra.txt was not pruned")
+	}
+	if info, err := os.Stat(filepath.Join(dst, "ro")); err != nil || info.Mode().Perm() != 0555 {
+		t.Errorf("directory mode was not restored: %v", info.Mode())
+	}
+}
+
+func TestCopyTreeKeepsDirInPlaceOfFile(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	os.WriteFile(filepath.Join(src, "x"), []byte("file"), 0666)
+	os.MkdirAll(filepath.Join(dst, "x"), 0777)
+	os.WriteFile(filepath.Join(dst, "x", "user.txt"), []byte("user"), 0666)
+
+	if _, err := CopyTree(src, dst, nil); !errors.Is(err, ErrDirNotFile) {
+		t.Errorf("CopyTree: %v", err)
+	}
+	if !FileExists(filepath.Join(dst, "x", "user.txt")) {
+		t.Fatal("CopyTree removed a destination directory")
+	}
+	if _, err := SyncTree(src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dst, "x")); string(b) != "file" {
+		t.Errorf("SyncTree must replace the directory: %q", b)
+	}
+}
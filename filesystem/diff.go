@@ -0,0 +1,214 @@
+package filesystem
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffOp is a single line operation in an edit script: ' ' keeps a line, '-'
+// removes a[ai], '+' inserts b[bi].
+type diffOp struct {
+	kind   byte
+	ai, bi int
+}
+
+// splitLines splits text into lines, keeping line terminators.
+func splitLines(s string) []string {
+	var lines []string
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+// maxDiffEdits limits the edit distance searched by diffLines, the trace
+// takes O(D²) memory.
+const maxDiffEdits = 1000
+
+// diffLines computes the shortest edit script that turns a into b (Myers'
+// O(ND) algorithm). The trace keeps only the diagonals reachable at each
+// step, so memory is O(D²) rather than O(D·(N+M)). Gives up and returns
+// false if the edit distance exceeds max_edits.
+func diffLines(a, b []string, max_edits int) ([]diffOp, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceLines(a, b), true
+	}
+	max := n + m
+	off := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		if d > max_edits {
+			return nil, false
+		}
+		// diagonals -d-1 .. d+1, indexed with k+d+1
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v, off := trace[d], d+1
+		k := x - y
+		var pk int
+		if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := v[off+pk]
+		py := px - pk
+		for x > px && y > py {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', x, y})
+		}
+		if d > 0 {
+			if x == px {
+				y--
+				ops = append(ops, diffOp{'+', x, y})
+			} else {
+				x--
+				ops = append(ops, diffOp{'-', x, y})
+			}
+		}
+		x, y = px, py
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops, true
+}
+
+// replaceLines is the edit script that removes all of a and inserts all of
+// b, used when the texts differ too much for diffLines.
+func replaceLines(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := range a {
+		ops = append(ops, diffOp{'-', i, 0})
+	}
+	for i := range b {
+		ops = append(ops, diffOp{'+', len(a), i})
+	}
+	return ops
+}
+
+// UnifiedDiff produces a unified diff between texts a and b, with the
+// specified number of context lines around the changes. Returns an empty
+// string if the texts are equal. Texts that differ in more than a thousand
+// lines produce a single hunk that replaces a with b.
+func UnifiedDiff(a_name, b_name string, a, b string, context int) string {
+	diff, _ := unifiedDiff(a_name, b_name, a, b, context, maxDiffEdits)
+	return diff
+}
+
+// unifiedDiff is UnifiedDiff that reports whether the edit distance fit into
+// max_edits.
+func unifiedDiff(a_name, b_name string, a, b string, context int, max_edits int) (string, bool) {
+	al, bl := splitLines(a), splitLines(b)
+	ops, ok := diffLines(al, bl, max_edits)
+	if !ok {
+		ops = replaceLines(al, bl)
+	}
+
+	sb := strings.Builder{}
+	line := func(prefix byte, s string) {
+		sb.WriteByte(prefix)
+		sb.WriteString(s)
+		if !strings.HasSuffix(s, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// extend the hunk while gaps between changes fit into the context
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			gap := end
+			for gap < len(ops) && ops[gap].kind == ' ' {
+				gap++
+			}
+			if gap == len(ops) || gap-end > 2*context {
+				end += context
+				if end > gap {
+					end = gap
+				}
+				break
+			}
+			end = gap
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", a_name, b_name)
+		}
+		astart, bstart := ops[start].ai, ops[start].bi
+		acount, bcount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				acount++
+			}
+			if op.kind != '-' {
+				bcount++
+			}
+		}
+		if acount > 0 {
+			astart++
+		}
+		if bcount > 0 {
+			bstart++
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", astart, acount, bstart, bcount)
+		for _, op := range ops[start:end] {
+			switch op.kind {
+			case ' ':
+				line(' ', al[op.ai])
+			case '-':
+				line('-', al[op.ai])
+			case '+':
+				line('+', bl[op.bi])
+			}
+		}
+		i = end
+	}
+	return sb.String(), ok
+}
@@ -0,0 +1,85 @@
+package filesystem
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"x\n", "x\n", ""},
+		{"", "x\ny\n", "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"},
+		{"x\ny\n", "", "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-x\n-y\n"},
+		{"1\n2\n3\n", "1\n2x\n3\n", "--- a\n+++ b\n@@ -1,3 +1,3 @@\n 1\n-2\n+2x\n 3\n"},
+		{"1\n2", "1\n2\n", "--- a\n+++ b\n@@ -1,2 +1,2 @@\n 1\n-2\n\\ No newline at end of file\n+2\n"},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"--- a\n+++ b\n@@ -1,2 +1,3 @@\n+0\n 1\n 2\n@@ -8,3 +9,2 @@\n 8\n 9\n-10\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := UnifiedDiff("a", "b", tt.a, tt.b, 2); got != tt.want {
+			t.Errorf("UnifiedDiff(%q, %q):\n%s\nwant:\n%s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	const n = 200000
+	a := make([]string, n)
+	for i := range a {
+		a[i] = strconv.Itoa(i) + "\n"
+	}
+	b := append([]string(nil), a...)
+	b[10] = "changed\n"
+	b = append(b[:n/2], b[n/2+5:]...)
+	b = append(b, "added\n")
+
+	ops, _ := diffLines(a, b, maxDiffEdits)
+	var got []string
+	edits := 0
+	for _, op := range ops {
+		switch op.kind {
+		case ' ':
+			got = append(got, a[op.ai])
+		case '+':
+			got = append(got, b[op.bi])
+			edits++
+		case '-':
+			edits++
+		}
+	}
+	if edits != 8 {
+		t.Errorf("edits: got %d, want 8", edits)
+	}
+	if strings.Join(got, "") != strings.Join(b, "") {
+		t.Error("edit script does not produce b")
+	}
+}
+
+func TestDiffLinesLimit(t *testing.T) {
+	const n = 20000
+	a := make([]string, n)
+	b := make([]string, n)
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i) + "\n"
+		b[i] = "b" + strconv.Itoa(i) + "\n"
+	}
+	if _, ok := diffLines(a, b, maxDiffEdits); ok {
+		t.Fatal("expected the edit distance limit to be exceeded")
+	}
+
+	diff := UnifiedDiff("a", "b", strings.Join(a, ""), strings.Join(b, ""), 3)
+	if !strings.HasPrefix(diff, "--- a\n+++ b\n@@ -1,20000 +1,20000 @@\n-a0\n") || strings.Count(diff, "\n") != 2*n+3 {
+		t.Errorf("expected a single replacing hunk, got %d lines", strings.Count(diff, "\n"))
+	}
+
+	if ops, ok := diffLines(nil, b, maxDiffEdits); !ok || len(ops) != n {
+		t.Errorf("new text must be diffed regardless of its size")
+	}
+}
@@ -0,0 +1,138 @@
+package filesystem
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// MatchGlob checks if the slash-separated relative path matches the pattern:
+//
+//   - pattern segments use path.Match syntax
+//   - '**' segment matches any number of path segments
+//   - patterns without a slash match the base name at any depth
+//   - patterns with a leading or inner slash are anchored at the root
+func MatchGlob(pattern string, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			pat = pat[1:]
+			if len(pat) == 0 {
+				return len(name) > 0
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// matchGlobEntry is MatchGlob with support for the trailing slash that
+// restricts the pattern to directories.
+func matchGlobEntry(pattern string, name string, is_dir bool) bool {
+	if strings.HasSuffix(pattern, "/") {
+		if !is_dir {
+			return false
+		}
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return MatchGlob(pattern, name)
+}
+
+func matchAnyGlob(patterns []string, name string, is_dir bool) bool {
+	for _, p := range patterns {
+		if matchGlobEntry(p, name, is_dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreRule is a single line of a .gitignore-style file.
+type ignoreRule struct {
+	pattern string
+	negate  bool
+}
+
+// ignoreList holds rules loaded from an ignore file.
+type ignoreList struct {
+	dir   string // slash-separated location of the ignore file relative to the walk root
+	rules []ignoreRule
+}
+
+// parseIgnore parses .gitignore-style content.
+func parseIgnore(dir string, content string) *ignoreList {
+	list := &ignoreList{dir: dir}
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		r := ignoreRule{}
+		if line[0] == '!' {
+			r.negate = true
+			line = line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:]
+		}
+		// patterns with an inner slash are relative to the ignore file
+		if inner := strings.TrimSuffix(line, "/"); strings.Contains(inner, "/") && !strings.HasPrefix(inner, "/") {
+			line = "/" + line
+		}
+		if line != "" && line != "/" {
+			r.pattern = line
+			list.rules = append(list.rules, r)
+		}
+	}
+	return list
+}
+
+func readIgnoreFile(fn string, dir string) (*ignoreList, error) {
+	buf, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	return parseIgnore(dir, string(buf)), nil
+}
+
+// ignored evaluates the rules of all the ignore files that apply to the
+// slash-separated path, the last matching rule wins.
+func ignored(lists []*ignoreList, rel string, is_dir bool) bool {
+	ret := false
+	for _, list := range lists {
+		sub := rel
+		if list.dir != "" {
+			if !strings.HasPrefix(rel, list.dir+"/") {
+				continue
+			}
+			sub = rel[len(list.dir)+1:]
+		}
+		for _, r := range list.rules {
+			if matchGlobEntry(r.pattern, sub, is_dir) {
+				ret = !r.negate
+			}
+		}
+	}
+	return ret
+}
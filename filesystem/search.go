@@ -11,7 +11,8 @@ type dirname = string
 type symlink = string
 
 // SearchDir returns the list of paths within the specified directory that pass
-// through the 'accept' callback. This is a non-recursive search.
+// through the 'accept' callback. This is a non-recursive search that returns
+// nil on errors, use Walk for recursive searches with error reporting.
 func SearchDir(dir dirname, accept func(os.FileInfo) bool) []filename {
 	d, err := os.Open(dir)
 	if err != nil {
@@ -44,44 +45,46 @@ func SearchDir(dir dirname, accept func(os.FileInfo) bool) []filename {
 func SearchFilesAndSymlinks(dirs []string, accept func(os.FileInfo) bool) map[filename][]symlink {
 	files := map[filename][]symlink{}
 	for _, dir := range dirs {
-		d, err := os.Open(dir)
-		if err != nil {
+		searchFilesAndSymlinks(dir, accept, files)
+	}
+	return files
+}
+
+func searchFilesAndSymlinks(dir dirname, accept func(os.FileInfo) bool, files map[filename][]symlink) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	finfos, err := d.Readdir(-1)
+	if err != nil {
+		return
+	}
+
+	for _, fi := range finfos {
+		if fi.IsDir() || !accept(fi) {
 			continue
 		}
-		defer d.Close()
-		finfos, err := d.Readdir(-1)
+		path, err := filepath.Abs(filepath.Join(dir, fi.Name()))
 		if err != nil {
 			continue
 		}
-
-		for _, fi := range finfos {
-			if !fi.IsDir() && accept(fi) {
-				fn := fi.Name()
-				path, err := filepath.Abs(filepath.Join(dir, fn))
+		if fi.Mode()&os.ModeSymlink == os.ModeSymlink {
+			real, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				continue
+			}
+			rfi, err := os.Stat(real)
+			if err == nil && !rfi.IsDir() && accept(rfi) {
+				real, err = filepath.Abs(real)
 				if err == nil {
-					if fi.Mode()&os.ModeSymlink == os.ModeSymlink {
-						real, err := filepath.EvalSymlinks(path)
-						if err == nil {
-							rfi, err := os.Stat(real)
-							if err == nil && !rfi.IsDir() && accept(rfi) {
-								real, err = filepath.Abs(real)
-								if err == nil {
-									ll := files[real]
-									files[real] = appendIfUnique(ll, path)
-								}
-							}
-						}
-					} else {
-						_, ok := files[path]
-						if !ok {
-							files[path] = []string{}
-						}
-					}
+					files[real] = appendIfUnique(files[real], path)
 				}
 			}
+		} else if _, ok := files[path]; !ok {
+			files[path] = []string{}
 		}
 	}
-	return files
 }
 
 func appendIfUnique(ss []string, s string) []string {
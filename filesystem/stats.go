@@ -21,6 +21,10 @@ var (
 	ErrDirExists         = errors.New("directory already exists")
 	ErrPathIsNotAbsolute = errors.New("path is not absolute")
 	ErrPathIsAbsolute    = errors.New("path is absolute")
+	ErrSymlinkCycle      = errors.New("symlink cycle")
+
+	ErrDestinationInsideSource = errors.New("destination is inside the source directory")
+	ErrSourceInsideDestination = errors.New("source is inside the destination directory")
 )
 
 // FileExists returns true if a file exists at the specified location.
@@ -0,0 +1,195 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WalkOptions configures Walk.
+type WalkOptions struct {
+	Include        []string // glob patterns for reported files, all files if empty (see MatchGlob)
+	Exclude        []string // glob patterns for skipped files and directories, trailing slash matches directories only
+	IgnoreFiles    []string // names of .gitignore-style files honored in each directory, e.g. ".gitignore"
+	FollowSymlinks bool     // descend into symlinked directories
+	Dirs           bool     // report directories too
+}
+
+// WalkEntry describes a file or a directory found by Walk.
+type WalkEntry struct {
+	Path   string      // path, starting with the walk root
+	Rel    string      // slash-separated path relative to the walk root
+	Info   fs.FileInfo // result of Lstat, nil when reporting errors that precede it
+	Target string      // absolute resolved path for symlinks, only when following them
+}
+
+// IsSymlink reports whether the entry is a symbolic link.
+func (e *WalkEntry) IsSymlink() bool {
+	return e.Info != nil && e.Info.Mode()&fs.ModeSymlink != 0
+}
+
+// WalkFunc is called by Walk for each entry. When err is not nil, it
+// describes a failure at the entry: returning nil continues the walk,
+// returning an error stops it. As with filepath.WalkDir, returning
+// filepath.SkipDir skips the directory (or the rest of the directory that
+// contains a file), and filepath.SkipAll stops the walk without an error.
+type WalkFunc = func(entry *WalkEntry, err error) error
+
+// Walk recursively traverses the root directory in lexical order and streams
+// the entries that pass the filters into fn. Errors are never dropped: they
+// are reported through fn along with the entry that caused them, including
+// ErrSymlinkCycle for symlinks that lead back into a directory that is being
+// walked.
+func Walk(root string, opts *WalkOptions, fn WalkFunc) error {
+	if opts == nil {
+		opts = &WalkOptions{}
+	}
+	w := walker{opts: opts, fn: fn}
+
+	entry := &WalkEntry{Path: root, Rel: "."}
+	info, err := os.Stat(root)
+	if err == nil && !info.IsDir() {
+		err = ErrFileNotDir
+	}
+	var real string
+	if err == nil {
+		if real, err = filepath.EvalSymlinks(root); err == nil {
+			real, err = filepath.Abs(real)
+		}
+	}
+	if err != nil {
+		return w.stop(fn(entry, err))
+	}
+
+	err = w.walkDir(root, real, "", nil, map[string]bool{real: true})
+	return w.stop(err)
+}
+
+type walker struct {
+	opts *WalkOptions
+	fn   WalkFunc
+}
+
+func (w *walker) stop(err error) error {
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// report passes an error to the callback, returns a non-nil error if the walk
+// needs to stop.
+func (w *walker) report(entry *WalkEntry, err error) error {
+	err = w.fn(entry, err)
+	if errors.Is(err, filepath.SkipDir) {
+		return nil
+	}
+	return err
+}
+
+func joinRel(rel, name string) string {
+	if rel == "" {
+		return name
+	}
+	return rel + "/" + name
+}
+
+func (w *walker) walkDir(dir, real, rel string, ignores []*ignoreList, ancestors map[string]bool) error {
+	for _, name := range w.opts.IgnoreFiles {
+		list, err := readIgnoreFile(filepath.Join(dir, name), rel)
+		if err == nil {
+			ignores = append(ignores[:len(ignores):len(ignores)], list)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			entry := &WalkEntry{Path: filepath.Join(dir, name), Rel: joinRel(rel, name)}
+			if err = w.report(entry, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		r := rel
+		if r == "" {
+			r = "."
+		}
+		return w.report(&WalkEntry{Path: dir, Rel: r}, err)
+	}
+
+	for _, d := range entries {
+		entry := &WalkEntry{
+			Path: filepath.Join(dir, d.Name()),
+			Rel:  joinRel(rel, d.Name()),
+		}
+		is_dir := d.IsDir()
+		is_link := d.Type()&fs.ModeSymlink != 0
+		if is_link && w.opts.FollowSymlinks {
+			if ti, err := os.Stat(entry.Path); err == nil && ti.IsDir() {
+				is_dir = true
+			}
+		}
+		if ignored(ignores, entry.Rel, is_dir) || matchAnyGlob(w.opts.Exclude, entry.Rel, is_dir) {
+			continue
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if err = w.report(entry, err); err != nil {
+				return err
+			}
+			continue
+		}
+		entry.Info = info
+
+		sub_real := filepath.Join(real, d.Name())
+		if is_link && w.opts.FollowSymlinks {
+			target, err := filepath.EvalSymlinks(entry.Path)
+			if err == nil {
+				target, err = filepath.Abs(target)
+			}
+			if err != nil {
+				if err = w.report(entry, err); err != nil {
+					return err
+				}
+				continue
+			}
+			entry.Target = target
+			sub_real = target
+		}
+
+		if is_dir {
+			if ancestors[sub_real] {
+				if err = w.report(entry, ErrSymlinkCycle); err != nil {
+					return err
+				}
+				continue
+			}
+			if w.opts.Dirs {
+				err = w.fn(entry, nil)
+				if errors.Is(err, filepath.SkipDir) {
+					continue
+				} else if err != nil {
+					return err
+				}
+			}
+			ancestors[sub_real] = true
+			err = w.walkDir(entry.Path, sub_real, entry.Rel, ignores, ancestors)
+			delete(ancestors, sub_real)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(w.opts.Include) > 0 && !matchAnyGlob(w.opts.Include, entry.Rel, false) {
+			continue
+		}
+		if err = w.fn(entry, nil); errors.Is(err, filepath.SkipDir) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
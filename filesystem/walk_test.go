@@ -0,0 +1,144 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "a/b/main.go", true},
+		{"*.go", "main.txt", false},
+		{"/*.go", "a/main.go", false},
+		{"a/*.go", "a/main.go", true},
+		{"a/*.go", "a/b/main.go", false},
+		{"a/**/*.go", "a/main.go", true},
+		{"a/**/*.go", "a/b/c/main.go", true},
+		{"**/testdata/*", "x/testdata/f", true},
+		{"**/testdata/*", "testdata/f", true},
+		{"a/**", "a/b/c", true},
+		{"a/**", "a", false},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+	for _, fn := range []string{
+		"a.go", "a_test.go", "b.txt", "build/out.go", "src/x.go", "src/gen/y.go", "src/gen/keep.go",
+	} {
+		fn = filepath.Join(dir, filepath.FromSlash(fn))
+		os.MkdirAll(filepath.Dir(fn), 0777)
+		os.WriteFile(fn, nil, 0666)
+	}
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("# comment\nbuild/\n*_test.go\n"), 0666)
+	os.WriteFile(filepath.Join(dir, "src", ".gitignore"), []byte("gen/*\n!gen/keep.go\n"), 0666)
+
+	var got []string
+	err := Walk(dir, &WalkOptions{
+		Include:     []string{"**/*.go", "*.go"},
+		IgnoreFiles: []string{".gitignore"},
+	}, func(entry *WalkEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, entry.Rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a.go src/gen/keep.go src/x.go"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+
+	if err = Walk(filepath.Join(dir, "a.go"), nil, func(*WalkEntry, error) error { return nil }); err != nil {
+		t.Errorf("errors must be passed to the callback: %v", err)
+	}
+	if err = Walk(filepath.Join(dir, "missing"), nil, func(_ *WalkEntry, err error) error { return err }); !os.IsNotExist(err) {
+		t.Errorf("missing root: %v", err)
+	}
+}
+
+func TestWalkSymlinkCycle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0777)
+	os.WriteFile(filepath.Join(dir, "a", "b", "f"), nil, 0666)
+	if err := os.Symlink("..", filepath.Join(dir, "a", "b", "up")); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	var cycles []string
+	err := Walk(dir, &WalkOptions{FollowSymlinks: true}, func(entry *WalkEntry, err error) error {
+		if errors.Is(err, ErrSymlinkCycle) {
+			cycles = append(cycles, entry.Rel)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		files = append(files, entry.Rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(files, " ") != "a/b/f" || strings.Join(cycles, " ") != "a/b/up" {
+		t.Errorf("files: %v, cycles: %v", files, cycles)
+	}
+}
+
+func TestWalkDanglingSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "f"), nil, 0666)
+	if err := os.Symlink("missing", filepath.Join(dir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+
+	walk := func(opts *WalkOptions) (files []string, errs []string) {
+		err := Walk(dir, opts, func(entry *WalkEntry, err error) error {
+			if err != nil {
+				errs = append(errs, entry.Rel)
+				return nil
+			}
+			files = append(files, entry.Rel)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	files, errs := walk(&WalkOptions{})
+	if strings.Join(files, " ") != "dangling f" || len(errs) != 0 {
+		t.Errorf("not following: files %v, errors %v", files, errs)
+	}
+	files, errs = walk(&WalkOptions{FollowSymlinks: true})
+	if strings.Join(files, " ") != "f" || strings.Join(errs, " ") != "dangling" {
+		t.Errorf("following: files %v, errors %v", files, errs)
+	}
+	files, errs = walk(&WalkOptions{FollowSymlinks: true, Exclude: []string{"dangling"}})
+	if strings.Join(files, " ") != "f" || len(errs) != 0 {
+		t.Errorf("excluded: files %v, errors %v", files, errs)
+	}
+}
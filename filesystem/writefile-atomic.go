@@ -0,0 +1,204 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// createTempSibling creates a new hidden temporary file next to the target,
+// perm is subject to umask (same as os.WriteFile)
+func createTempSibling(target string, kind string, perm os.FileMode) (*os.File, string, error) {
+	dir, base := filepath.Split(target)
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i < 100; i++ {
+		tmp := filepath.Join(dir, "."+base+kind+strconv.FormatUint(uint64(rnd.Uint32()), 36))
+		f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if err == nil {
+			return f, tmp, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, "", err
+		}
+	}
+	return nil, "", os.ErrExist
+}
+
+// resolveTarget follows symlinks, so that the file they point to gets
+// replaced instead of the link itself
+func resolveTarget(fn string) string {
+	if real, err := filepath.EvalSymlinks(fn); err == nil {
+		return real
+	}
+	return fn
+}
+
+// stageFile writes data into a temporary file next to the target and flushes
+// it to disk. If the target exists, the temporary file gets its permissions
+// and (where possible) ownership, otherwise it is created with perm (subject
+// to umask).
+func stageFile(target string, data []byte, perm os.FileMode) (tmp string, err error) {
+	original, statErr := os.Stat(target)
+
+	f, tmp, err := createTempSibling(target, ".tmp", perm)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+			tmp = ""
+		}
+	}()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
+
+	if statErr == nil {
+		if err = os.Chmod(tmp, original.Mode().Perm()); err != nil {
+			return
+		}
+		copyOwner(tmp, original)
+	}
+	return
+}
+
+// writeFileAtomic writes data into a temporary file next to the target,
+// flushes it to disk, and renames it over the target, so that the target
+// either keeps its original content or gets the new content in full.
+func writeFileAtomic(fn string, data []byte, perm os.FileMode) error {
+	target := resolveTarget(fn)
+	tmp, err := stageFile(target, data, perm)
+	if err != nil {
+		return err
+	}
+	if err = renameFile(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// best effort: persist the rename
+	syncDir(filepath.Dir(target))
+	return nil
+}
+
+// copyFileAtomic streams the content of src into a temporary file next to the
+// target and renames it over the target
+func copyFileAtomic(src string, target string, perm os.FileMode) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	f, tmp, err := createTempSibling(target, ".tmp", perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	_, err = io.Copy(f, in)
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
+	if err = os.Chmod(tmp, perm); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, target); err != nil {
+		return
+	}
+
+	// best effort: persist the rename
+	syncDir(filepath.Dir(target))
+	return nil
+}
+
+// linkOrCopy makes a backup of fn while leaving fn in place: creates a hard
+// link if possible, otherwise copies the content and permissions
+func linkOrCopy(fn string, backup_fn string) (err error) {
+	if os.Link(fn, backup_fn) == nil {
+		return nil
+	}
+	src, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(backup_fn, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(backup_fn)
+	}
+	return err
+}
+
+// preserveOriginal keeps the current content of fn in a hidden temporary
+// file next to it, used for rolling back
+func preserveOriginal(fn string) (string, error) {
+	f, tmp, err := createTempSibling(fn, ".orig", 0600)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	os.Remove(tmp)
+	if err = linkOrCopy(fn, tmp); err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(fn); err == nil {
+		os.Chmod(tmp, info.Mode().Perm())
+		copyOwner(tmp, info)
+	}
+	return tmp, nil
+}
+
+// copyOver atomically replaces target with a copy of src
+func copyOver(src string, target string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	tmp, err := stageFile(target, data, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,230 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupPolicy configures naming, location and retention of backups. Unlike a
+// plain BackupNameGenerator, it allows discovering existing backups of a file
+// and pruning old ones.
+//
+// Retention limits are applied to the backups of each file independently;
+// zero values do not limit. A backup is removed if it falls outside of any of
+// the limits.
+type BackupPolicy struct {
+	Suffix      string // injected into backup filenames, e.g. ".bak"
+	Timestamp   string // timestamp format (see BackupNameTimestamp), numbered backups if empty
+	Dir         string // optional backup directory, relative to the directory of the original file
+	MaxAttempts int    // naming attempts, defaults to 100
+
+	KeepLast     int           // keep the specified number of most recent backups
+	KeepNewer    time.Duration // keep backups that are newer than the specified duration
+	MaxTotalSize int64         // keep most recent backups that fit into the specified total size
+}
+
+// BackupFile describes an existing backup.
+type BackupFile struct {
+	Path string
+	Size int64
+	Time time.Time // timestamp from the backup name, or the time the backup was made (modification time)
+}
+
+var errAmbiguousBackupNaming = errors.New("backup policy requires a suffix or a separate directory")
+var errNoBackups = errors.New("no backups found")
+
+// Generator produces the backup name generator for the policy.
+func (p *BackupPolicy) Generator() BackupNameGenerator {
+	n := p.MaxAttempts
+	if n <= 0 {
+		n = 100
+	}
+	var gen BackupNameGenerator
+	if p.Timestamp != "" {
+		gen = BackupNameTimestamp(p.Suffix, p.Timestamp, n)
+	} else {
+		gen = BackupNameNumeric(p.Suffix, n)
+	}
+	if p.Dir != "" {
+		gen = BackupNameInDir(p.Dir, gen)
+	}
+	return gen
+}
+
+// parseName checks if the backup filename matches the policy naming for the
+// original file and extracts the timestamp. When backups are located next to
+// the original file (same_dir), the original itself is not a backup.
+func (p *BackupPolicy) parseName(original_base string, name string, same_dir bool) (ts time.Time, ok bool) {
+	ext := filepath.Ext(original_base)
+	prefix := original_base[:len(original_base)-len(ext)] + p.Suffix
+	if (same_dir && name == original_base) || len(name) < len(prefix)+len(ext) ||
+		!strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+		return
+	}
+	middle := name[len(prefix) : len(name)-len(ext)]
+
+	// try the whole middle first, the timestamp format may contain
+	// underscores, then without the attempt number
+	if ts, ok = p.parseMiddle(middle); ok {
+		return
+	}
+	if i := strings.LastIndexByte(middle, '_'); i >= 0 {
+		if _, err := strconv.Atoi(middle[i+1:]); err == nil {
+			return p.parseMiddle(middle[:i])
+		}
+	}
+	return
+}
+
+func (p *BackupPolicy) parseMiddle(middle string) (ts time.Time, ok bool) {
+	if p.Timestamp == "" {
+		return ts, middle == ""
+	}
+	ts, err := time.ParseInLocation(p.Timestamp, middle, time.Local)
+	return ts, err == nil
+}
+
+// List discovers existing backups of the file, most recent first.
+func (p *BackupPolicy) List(fn string) ([]BackupFile, error) {
+	if p.Suffix == "" && p.Dir == "" {
+		return nil, errAmbiguousBackupNaming
+	}
+	dir := backupDir(fn, p.Dir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Base(fn)
+	same_dir := filepath.Clean(dir) == filepath.Clean(filepath.Dir(fn))
+	var list []BackupFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		ts, ok := p.parseName(base, e.Name(), same_dir)
+		if !ok {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		if ts.IsZero() {
+			ts = info.ModTime()
+		}
+		list = append(list, BackupFile{
+			Path: filepath.Join(dir, e.Name()),
+			Size: info.Size(),
+			Time: ts,
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if !list[i].Time.Equal(list[j].Time) {
+			return list[i].Time.After(list[j].Time)
+		}
+		return list[i].Path > list[j].Path
+	})
+	return list, nil
+}
+
+// Prune removes backups of the file that fall outside of the retention limits,
+// returns the names of removed backups.
+func (p *BackupPolicy) Prune(fn string) (removed []string, err error) {
+	return p.prune(fn, "")
+}
+
+// prune is Prune that never removes the specified backup.
+func (p *BackupPolicy) prune(fn string, keep_fn string) (removed []string, err error) {
+	list, err := p.List(fn)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	kept := 0
+	total := int64(0)
+	for _, b := range list {
+		keep := (p.KeepLast <= 0 || kept < p.KeepLast) &&
+			(p.KeepNewer <= 0 || now.Sub(b.Time) <= p.KeepNewer) &&
+			(p.MaxTotalSize <= 0 || total+b.Size <= p.MaxTotalSize)
+		if !keep && b.Path != keep_fn {
+			if e := os.Remove(b.Path); e == nil {
+				removed = append(removed, b.Path)
+				continue
+			} else if err == nil {
+				err = e
+			}
+		}
+		kept++
+		total += b.Size
+	}
+	return removed, err
+}
+
+// RestoreLatest restores the file from its most recent backup, see
+// RestoreBackup.
+func (p *BackupPolicy) RestoreLatest(fn string, opts *WriteOptions) (WriteFileStatus, error) {
+	list, err := p.List(fn)
+	if err != nil {
+		return Failed, err
+	}
+	if len(list) == 0 {
+		return Failed, errNoBackups
+	}
+	return RestoreBackup(list[0].Path, fn, opts)
+}
+
+// RestoreBackup overwrites the file with the content of the backup, which
+// stays intact. The content is written with WriteFileEx, so the current
+// content can be backed up as well.
+func RestoreBackup(backup_fn string, fn string, opts *WriteOptions) (WriteFileStatus, error) {
+	data, err := os.ReadFile(backup_fn)
+	if err != nil {
+		return Failed, err
+	}
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	if opts.Perm == 0 {
+		if info, err := os.Stat(backup_fn); err == nil {
+			o := *opts
+			o.Perm = info.Mode().Perm()
+			opts = &o
+		}
+	}
+	return WriteFileEx(fn, data, opts)
+}
+
+// pruneBackups applies the retention policy after a backup was made, the new
+// backup is kept regardless of the limits. The backup gets the current
+// modification time: it still has the time of the original content, while
+// the policy needs the time the backup was made.
+func pruneBackups(fn string, backup_fn string, policy *BackupPolicy, onFeedback WriteFeedbackProc) {
+	if policy == nil {
+		return
+	}
+	now := time.Now()
+	os.Chtimes(backup_fn, now, now)
+	removed, _ := policy.prune(fn, backup_fn)
+	if onFeedback != nil {
+		for _, b := range removed {
+			onFeedback(FeedbackBackupPruned, b)
+		}
+	}
+}
+
+// backupGenerator picks the generator from the policy, if specified.
+func backupGenerator(gen BackupNameGenerator, policy *BackupPolicy) BackupNameGenerator {
+	if policy != nil {
+		return policy.Generator()
+	}
+	return gen
+}
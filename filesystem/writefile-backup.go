@@ -3,7 +3,11 @@ package filesystem
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -36,6 +40,7 @@ func BackupNameNumeric(suffix string, max_attempts int) BackupNameGenerator {
 // suffix into the original filename:
 //
 //   - naming pattern: file<suffix>timestamp.ext
+//   - n-th attempt: file<suffix>timestamp_n.ext (when the timestamp is already claimed)
 //   - uses time.Now().Format(timestamp_format)
 //   - stops after max_attempts if generated filenames are already claimed by existing files
 func BackupNameTimestamp(suffix string, timestamp_format string, max_attempts int) BackupNameGenerator {
@@ -46,6 +51,121 @@ func BackupNameTimestamp(suffix string, timestamp_format string, max_attempts in
 		ext := filepath.Ext(original_fn)
 		without_ext := original_fn[:len(original_fn)-len(ext)]
 		ts := time.Now().Format(timestamp_format)
-		return fmt.Sprintf("%s%s%s%s", without_ext, suffix, ts, ext)
+		if n <= 1 {
+			return fmt.Sprintf("%s%s%s%s", without_ext, suffix, ts, ext)
+		} else {
+			return fmt.Sprintf("%s%s%s_%d%s", without_ext, suffix, ts, n, ext)
+		}
+	}
+}
+
+// BackupNameInDir wraps a backup name generator to place backups into a
+// separate directory. Relative directories are resolved against the directory
+// of the original file.
+func BackupNameInDir(dir string, gen BackupNameGenerator) BackupNameGenerator {
+	return func(original_fn string, n int) string {
+		fn := gen(original_fn, n)
+		if fn == "" {
+			return ""
+		}
+		return filepath.Join(backupDir(original_fn, dir), filepath.Base(fn))
+	}
+}
+
+func backupDir(original_fn string, dir string) string {
+	if dir == "" {
+		return filepath.Dir(original_fn)
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(original_fn), dir)
+}
+
+// makeBackup asks the generator for backup names until it manages to create
+// the backup under one of them. The backup function must fail with
+// fs.ErrExist if the name is already claimed, so that concurrent writers never
+// end up with the same backup name.
+func makeBackup(fn string, gen BackupNameGenerator, backup func(backup_fn string) error) (string, error) {
+	for attempt := 1; ; attempt++ {
+		backup_fn := gen(fn, attempt)
+		if backup_fn == "" {
+			return "", errFileBackupFailed
+		}
+		if err := os.MkdirAll(filepath.Dir(backup_fn), 0777); err != nil {
+			return backup_fn, err
+		}
+		err := backup(backup_fn)
+		if !errors.Is(err, fs.ErrExist) {
+			return backup_fn, err
+		}
 	}
 }
+
+// renameExclusive moves fn to backup_fn, fails with fs.ErrExist if backup_fn
+// already exists; the name is claimed with an empty placeholder first
+func renameExclusive(fn string, backup_fn string) error {
+	f, err := os.OpenFile(backup_fn, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	if err = os.Rename(fn, backup_fn); err != nil {
+		os.Remove(backup_fn)
+	}
+	return err
+}
+
+// backupMatcher derives the naming scheme from the first name the generator
+// produces for fn. Matching names are located in the same directory, share
+// the name and the extension of the original file, and have the same middle
+// part, except for digits (e.g. timestamps) and an optional _n attempt
+// number. Returns an empty dir if the scheme can not be derived.
+func backupMatcher(fn string, gen BackupNameGenerator) (dir string, match func(name string) bool) {
+	first := gen(fn, 1)
+	if first == "" {
+		return "", nil
+	}
+	dir, first = filepath.Split(first)
+	base := filepath.Base(fn)
+	ext := filepath.Ext(base)
+	prefix := base[:len(base)-len(ext)]
+	if len(first) < len(prefix)+len(ext) || !strings.HasPrefix(first, prefix) || !strings.HasSuffix(first, ext) {
+		return "", nil
+	}
+	same_dir := filepath.Clean(dir) == filepath.Clean(filepath.Dir(fn))
+	scheme := first[len(prefix) : len(first)-len(ext)]
+
+	like := func(middle string) bool {
+		if len(middle) != len(scheme) {
+			return false
+		}
+		for i := 0; i < len(middle); i++ {
+			a, b := middle[i], scheme[i]
+			if a != b && !(isDigit(a) && isDigit(b)) {
+				return false
+			}
+		}
+		return true
+	}
+	return dir, func(name string) bool {
+		if (same_dir && name == base) || len(name) < len(prefix)+len(ext) ||
+			!strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			return false
+		}
+		middle := name[len(prefix) : len(name)-len(ext)]
+		if like(middle) {
+			return true
+		}
+		if i := strings.LastIndexByte(middle, '_'); i >= 0 {
+			if _, err := strconv.Atoi(middle[i+1:]); err == nil {
+				return like(middle[:i])
+			}
+		}
+		return false
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
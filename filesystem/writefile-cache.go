@@ -0,0 +1,191 @@
+package filesystem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MatchCache remembers content hashes of files, so that FileContentMatch-like
+// checks can decide that a file is unchanged without reading it. Entries are
+// keyed by path and validated with size, modification time and (where
+// available) inode number.
+//
+// Metadata is considered ambiguous (racy) when the file was modified shortly
+// before its hash was recorded (the modification could be missed due to coarse
+// timestamp granularity); such files are compared in full once more, and the
+// confirmed entry is stored again with a fresh record time, so that it can be
+// trusted from then on. Entries made by Record right after writing are racy
+// until the next Match.
+//
+// A nil *MatchCache is valid and performs full comparisons. The cache is safe
+// for concurrent use.
+type MatchCache struct {
+	fn      string
+	mu      sync.Mutex
+	entries map[string]matchCacheEntry
+	dirty   bool
+}
+
+type matchCacheEntry struct {
+	Size     int64  `json:"size"`
+	ModTime  int64  `json:"mtime"`           // unix nanoseconds
+	Inode    uint64 `json:"inode,omitempty"` // zero if not available
+	Hash     string `json:"sha256"`
+	Recorded int64  `json:"recorded"` // unix nanoseconds
+}
+
+// cacheRacyWindow accounts for coarse file timestamp granularity.
+const cacheRacyWindow = 2 * time.Second
+
+// cacheNow is replaced in tests to simulate the passage of time
+var cacheNow = time.Now
+
+// OpenMatchCache loads the cache from the specified file. Missing or
+// unreadable cache content is not an error, the cache starts empty.
+func OpenMatchCache(fn string) *MatchCache {
+	c := &MatchCache{fn: fn, entries: map[string]matchCacheEntry{}}
+	if buf, err := os.ReadFile(fn); err == nil {
+		if json.Unmarshal(buf, &c.entries) != nil {
+			c.entries = map[string]matchCacheEntry{}
+		}
+	}
+	return c
+}
+
+// Save writes the cache back into its file if it was modified.
+func (c *MatchCache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	buf, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err = writeFileAtomic(c.fn, buf, 0666); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+func hashString(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func cacheKey(fn string) string {
+	if abs, err := filepath.Abs(fn); err == nil {
+		return abs
+	}
+	return fn
+}
+
+func makeCacheEntry(info fs.FileInfo, hash string) matchCacheEntry {
+	return matchCacheEntry{
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UnixNano(),
+		Inode:    fileInode(info),
+		Hash:     hash,
+		Recorded: cacheNow().UnixNano(),
+	}
+}
+
+// lookup returns the cached hash if the entry is still valid for the file;
+// racy is set if the metadata matches, but the entry can not be trusted
+// without a full comparison.
+func (c *MatchCache) lookup(key string, info fs.FileInfo) (hash string, ok bool, racy bool) {
+	c.mu.Lock()
+	e, found := c.entries[key]
+	c.mu.Unlock()
+	mtime := info.ModTime().UnixNano()
+	if !found || e.Size != info.Size() || e.Inode != fileInode(info) || mtime == 0 || e.ModTime != mtime {
+		return "", false, false
+	}
+	if mtime+int64(cacheRacyWindow) > e.Recorded {
+		return e.Hash, false, true
+	}
+	return e.Hash, true, false
+}
+
+func (c *MatchCache) store(key string, e matchCacheEntry) {
+	c.mu.Lock()
+	c.entries[key] = e
+	c.dirty = true
+	c.mu.Unlock()
+}
+
+// Match checks if the file has content that matches the specified data (see
+// FileContentMatch), using cached hashes when file metadata is unchanged.
+// Files that need a full comparison get their hashes cached.
+func (c *MatchCache) Match(fn string, data []byte) (bool, error) {
+	if c == nil {
+		return FileContentMatch(fn, data)
+	}
+	info, err := os.Stat(fn)
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() {
+		return FileContentMatch(fn, data)
+	}
+	if info.Size() != int64(len(data)) {
+		return false, nil
+	}
+	key := cacheKey(fn)
+	hash, ok, racy := c.lookup(key, info)
+	if ok {
+		return hash == hashString(data), nil
+	}
+
+	content, err := os.ReadFile(fn)
+	if err != nil {
+		return false, err
+	}
+	// only cache if the file did not change while reading; a racy entry that
+	// is confirmed by the content is stored again with a fresh record time
+	if after, err := os.Stat(fn); err == nil && after.Size() == info.Size() && after.ModTime().Equal(info.ModTime()) {
+		e := makeCacheEntry(info, hashString(content))
+		if !racy || e.Hash != hash || e.Recorded >= e.ModTime+int64(cacheRacyWindow) {
+			c.store(key, e)
+		}
+	}
+	return bytes.Equal(content, data), nil
+}
+
+// Record caches the hash of data that was just written into the file.
+func (c *MatchCache) Record(fn string, data []byte) {
+	if c == nil {
+		return
+	}
+	info, err := os.Stat(fn)
+	if err != nil || info.Size() != int64(len(data)) {
+		return
+	}
+	c.store(cacheKey(fn), makeCacheEntry(info, hashString(data)))
+}
+
+// Forget removes the file from the cache.
+func (c *MatchCache) Forget(fn string) {
+	if c == nil {
+		return
+	}
+	key := cacheKey(fn)
+	c.mu.Lock()
+	if _, ok := c.entries[key]; ok {
+		delete(c.entries, key)
+		c.dirty = true
+	}
+	c.mu.Unlock()
+}
@@ -17,6 +17,19 @@ const (
 	FeedbackBackupSucceded
 	FeedbackBackupFailed
 	FeedbackBackupRestoreFailed
+
+	FeedbackRollbackBegin
+	FeedbackRollbackSucceded
+	FeedbackRollbackFailed
+
+	FeedbackRemoveBegin
+	FeedbackRemoveSucceded
+	FeedbackRemoveFailed
+
+	FeedbackBackupPruned
+
+	FeedbackDryRunWrite
+	FeedbackDryRunRemove
 )
 
 type WriteFeedbackProc = func(fb WriteFeedback, fn string)
@@ -43,6 +56,28 @@ func MakeWriteFileFeedback(w io.Writer) WriteFeedbackProc {
 		case FeedbackBackupRestoreFailed:
 			fmt.Fprintf(w, "CRITICAL: failed to restore from backup\n")
 			fmt.Fprintf(w, "CRITICAL: backed up content still available in %s\n", fn)
+
+		case FeedbackRollbackBegin:
+			fmt.Fprintf(w, "rolling back %s ... ", fn)
+		case FeedbackRollbackFailed:
+			fmt.Fprintf(w, "FAILED\n")
+		case FeedbackRollbackSucceded:
+			fmt.Fprintf(w, "SUCCEEDED\n")
+
+		case FeedbackRemoveBegin:
+			fmt.Fprintf(w, "removing stale %s ... ", fn)
+		case FeedbackRemoveFailed:
+			fmt.Fprintf(w, "FAILED\n")
+		case FeedbackRemoveSucceded:
+			fmt.Fprintf(w, "SUCCEEDED\n")
+
+		case FeedbackBackupPruned:
+			fmt.Fprintf(w, "removed old backup %s\n", fn)
+
+		case FeedbackDryRunWrite:
+			fmt.Fprintf(w, "would write %s\n", fn)
+		case FeedbackDryRunRemove:
+			fmt.Fprintf(w, "would remove %s\n", fn)
 		}
 	}
 }
@@ -50,3 +50,37 @@ func FileContentMatch(fn string, data []byte) (bool, error) {
 	}
 	return match, err
 }
+
+// filesMatch compares the content of two files of the same size without
+// loading them into memory
+func filesMatch(fn1, fn2 string) (bool, error) {
+	f1, err := os.Open(fn1)
+	if err != nil {
+		return false, err
+	}
+	defer f1.Close()
+	f2, err := os.Open(fn2)
+	if err != nil {
+		return false, err
+	}
+	defer f2.Close()
+
+	const cap = 32 * 1024
+	var b1, b2 [cap]byte
+	for {
+		n1, err1 := io.ReadFull(f1, b1[:])
+		n2, err2 := io.ReadFull(f2, b2[:])
+		if n1 != n2 || !bytes.Equal(b1[:n1], b2[:n2]) {
+			return false, nil
+		}
+		if err1 == io.EOF || err1 == io.ErrUnexpectedEOF {
+			return err2 == io.EOF || err2 == io.ErrUnexpectedEOF, nil
+		}
+		if err1 != nil {
+			return false, err1
+		}
+		if err2 != nil {
+			return false, err2
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !windows
+// +build !windows
+
+package filesystem
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner transfers ownership of the original file to fn, ignores errors
+// since changing ownership requires privileges
+func copyOwner(fn string, original os.FileInfo) {
+	if st, ok := original.Sys().(*syscall.Stat_t); ok {
+		os.Lchown(fn, int(st.Uid), int(st.Gid))
+	}
+}
+
+// syncDir flushes directory entries to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if e := d.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// fileInode returns the inode number of the file
+func fileInode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,17 @@
+package filesystem
+
+import "os"
+
+// copyOwner is a no-op on windows hosts, where files inherit access control
+// lists from their directory
+func copyOwner(fn string, original os.FileInfo) {}
+
+// syncDir is a no-op on windows hosts, where directories can not be flushed
+func syncDir(dir string) error {
+	return nil
+}
+
+// fileInode is not available from os.FileInfo on windows hosts
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
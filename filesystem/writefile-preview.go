@@ -0,0 +1,145 @@
+package filesystem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adnsv/go-utils/ansi"
+)
+
+// PreviewOptions configures dry-run previews of pending writes.
+type PreviewOptions struct {
+	Context int  // number of unchanged lines around each change, defaults to 3
+	Color   bool // colorize the output with ANSI escape sequences
+}
+
+// isBinary reports whether data does not look like text: contains NUL bytes
+// or is not valid UTF-8.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
+func shortHash(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:6])
+}
+
+// Preview describes the changes that writing out the entry would make,
+// without touching the disk. Relies on the status obtained with UpdateStatus:
+//
+//   - Creating and Overwriting entries produce a unified diff for text files
+//     or a size and hash summary for binary files and for text files with
+//     too many changed lines
+//   - other entries produce an empty string
+func (en *WriteFileEntry) Preview(opts *PreviewOptions) (string, error) {
+	if en.status != Creating && en.status != Overwriting {
+		return "", nil
+	}
+	if opts == nil {
+		opts = &PreviewOptions{}
+	}
+	context := opts.Context
+	if context <= 0 {
+		context = 3
+	}
+
+	var original []byte
+	a_name := "/dev/null"
+	if en.status == Overwriting {
+		var err error
+		if original, err = os.ReadFile(en.FilePath); err != nil {
+			return "", err
+		}
+		a_name = en.FilePath
+	}
+	payload := en.Payload.Bytes()
+
+	kind := "Binary file"
+	if !isBinary(original) && !isBinary(payload) {
+		diff, ok := unifiedDiff(a_name, en.FilePath, string(original), string(payload), context, maxDiffEdits)
+		if ok {
+			if opts.Color {
+				diff = colorizeDiff(diff)
+			}
+			return diff, nil
+		}
+		kind = "File (too many changes for a diff)"
+	}
+
+	s := fmt.Sprintf("%s %s: ", kind, en.FilePath)
+	if en.status == Creating {
+		s += fmt.Sprintf("new, %s (sha256 %s)\n", ByteSizeStr(uint64(len(payload))), shortHash(payload))
+	} else {
+		s += fmt.Sprintf("%s (sha256 %s) -> %s (sha256 %s)\n",
+			ByteSizeStr(uint64(len(original))), shortHash(original),
+			ByteSizeStr(uint64(len(payload))), shortHash(payload))
+	}
+	if opts.Color {
+		s = ansi.Bold + strings.TrimSuffix(s, "\n") + ansi.Reset + "\n"
+	}
+	return s, nil
+}
+
+// colorizeDiff highlights unified diff lines.
+func colorizeDiff(diff string) string {
+	sb := strings.Builder{}
+	for _, line := range splitLines(diff) {
+		text := strings.TrimSuffix(line, "\n")
+		var style string
+		switch {
+		case strings.HasPrefix(text, "--- ") || strings.HasPrefix(text, "+++ "):
+			style = ansi.Bold
+		case strings.HasPrefix(text, "@@"):
+			style = ansi.ForegroundBase(ansi.Cyan)
+		case strings.HasPrefix(text, "-"):
+			style = ansi.ForegroundBase(ansi.Red)
+		case strings.HasPrefix(text, "+"):
+			style = ansi.ForegroundBase(ansi.Green)
+		}
+		if style == "" {
+			sb.WriteString(line)
+			continue
+		}
+		sb.WriteString(style)
+		sb.WriteString(text)
+		sb.WriteString(ansi.Reset)
+		sb.WriteString(line[len(text):])
+	}
+	return sb.String()
+}
+
+// Preview writes out a dry-run preview of all pending entries and stale files
+// into w, without touching the disk. Call UpdateStatus first to detect pending
+// entries.
+func (v WriteFileset) Preview(w io.Writer, opts *PreviewOptions) error {
+	if err := v.Errors(); err != nil {
+		return err
+	}
+	for _, en := range v.Entries {
+		s, err := en.Preview(opts)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(w, s); err != nil {
+			return err
+		}
+	}
+	for _, r := range v.Roots {
+		for _, fn := range r.stale {
+			s := "Stale file " + fn + ": will be removed"
+			if opts != nil && opts.Color {
+				s = ansi.ForegroundBase(ansi.Red) + s + ansi.Reset
+			}
+			if _, err := io.WriteString(w, s+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -2,10 +2,12 @@ package filesystem
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"golang.org/x/exp/slices"
 )
@@ -18,6 +20,9 @@ type WriteFileEntry struct {
 	Perm     os.FileMode
 	Backup   BackupNameGenerator
 	Tag      string
+	Cache    *MatchCache // optional content hash cache, see MatchCache
+
+	BackupPolicy *BackupPolicy // overrides Backup, see WriteOptions.BackupPolicy
 
 	status WriteFileStatus
 	err    error
@@ -42,7 +47,12 @@ func (en *WriteFileEntry) LastError() error {
 var errMissingFileBuffer = errors.New("missing file buffer")
 var errEmptyFilePath = errors.New("empty filepath")
 
+// UpdateStatus checks if the entry needs to be written.
 func (en *WriteFileEntry) UpdateStatus() {
+	en.updateStatus(en.Cache)
+}
+
+func (en *WriteFileEntry) updateStatus(cache *MatchCache) {
 	en.status = StatErr
 	en.err = nil
 
@@ -66,7 +76,7 @@ func (en *WriteFileEntry) UpdateStatus() {
 		return
 	}
 
-	match, err := FileContentMatch(en.FilePath, en.Payload.Bytes())
+	match, err := cache.Match(en.FilePath, en.Payload.Bytes())
 	if err != nil {
 		en.status = StatErr
 		en.err = err
@@ -84,6 +94,24 @@ func (en *WriteFileEntry) UpdateStatus() {
 type WriteFileset struct {
 	Entries    []*WriteFileEntry
 	OnFeedback WriteFeedbackProc
+	Atomic     bool // write entries atomically (see WriteOptions.Atomic)
+
+	// Transactional enables all-or-nothing writing: all pending entries are
+	// staged first, then swapped in; if any of them fails, the ones already
+	// written are rolled back to their original content.
+	Transactional bool
+
+	// Roots lists directories owned by the set, see OutputRoot.
+	Roots []*OutputRoot
+
+	// Cache is used for entries that do not have their own cache, see
+	// MatchCache. Call Cache.Save after writing to persist it.
+	Cache *MatchCache
+
+	// Workers limits the number of entries that are checked and written
+	// concurrently, entries are processed sequentially if Workers <= 1.
+	// Feedback is delivered in entry order regardless.
+	Workers int
 }
 
 // Add adds new entry into the set.
@@ -134,12 +162,30 @@ func (v WriteFileset) Errors() error {
 	}
 }
 
-// UpdateStatus updates pending overwrite status for all entries in the set.
+// UpdateStatus updates pending overwrite status for all entries in the set
+// and detects stale files under the owned roots.
 func (v WriteFileset) UpdateStatus() error {
-	for _, en := range v.Entries {
-		en.UpdateStatus()
+	return v.UpdateStatusContext(context.Background())
+}
+
+// UpdateStatusContext is a version of UpdateStatus that stops checking
+// entries when ctx is cancelled.
+func (v WriteFileset) UpdateStatusContext(ctx context.Context) error {
+	err := v.forEach(ctx, v.Entries, func(en *WriteFileEntry, fb WriteFeedbackProc) {
+		en.updateStatus(v.cacheFor(en))
+	})
+	if err != nil {
+		return err
 	}
-	return v.Errors()
+	if err := v.Errors(); err != nil {
+		return err
+	}
+	for _, r := range v.Roots {
+		if err := r.update(v.Entries, v.Cache); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Count counts the entries with have the specified status value.
@@ -166,36 +212,160 @@ func (v WriteFileset) CountPending() int {
 
 // WriteTagged writes out pending entries that have matching tags.
 func (v WriteFileset) WriteTagged(tags ...string) error {
+	return v.WriteTaggedContext(context.Background(), tags...)
+}
+
+// WriteTaggedContext is a version of WriteTagged that stops writing entries
+// when ctx is cancelled.
+func (v WriteFileset) WriteTaggedContext(ctx context.Context, tags ...string) error {
+	return v.write(ctx, func(en *WriteFileEntry) bool {
+		return slices.Contains(tags, en.Tag)
+	})
+}
+
+// WritePending writes out all pending entries. On success, removes stale
+// files under the owned roots and updates their manifests.
+func (v WriteFileset) WritePending() error {
+	return v.WritePendingContext(context.Background())
+}
+
+// WritePendingContext is a version of WritePending that stops writing entries
+// when ctx is cancelled. Entries that were not written retain their pending
+// status.
+func (v WriteFileset) WritePendingContext(ctx context.Context) error {
+	if err := v.write(ctx, func(en *WriteFileEntry) bool { return true }); err != nil {
+		return err
+	}
+	return v.cleanup()
+}
+
+func (v WriteFileset) write(ctx context.Context, filter func(en *WriteFileEntry) bool) error {
 	if err := v.Errors(); err != nil {
 		return err
 	}
+	var pending []*WriteFileEntry
 	for _, en := range v.Entries {
-		if (en.status == Creating || en.status == Overwriting) && slices.Contains(tags, en.Tag) {
-			opts := WriteOptions{
-				Perm:       en.Perm,
-				Backup:     en.Backup,
-				OnFeedback: v.OnFeedback,
-			}
-			en.status, en.err = WriteFileEx(en.FilePath, en.Payload.Bytes(), &opts)
+		if (en.status == Creating || en.status == Overwriting) && filter(en) {
+			pending = append(pending, en)
+		}
+	}
+	if v.Transactional {
+		if err := v.commit(ctx, pending); err != nil {
+			return err
+		}
+		return v.Errors()
+	}
+	err := v.forEach(ctx, pending, func(en *WriteFileEntry, fb WriteFeedbackProc) {
+		opts := WriteOptions{
+			Perm:         en.Perm,
+			Backup:       en.Backup,
+			BackupPolicy: en.BackupPolicy,
+			OnFeedback:   fb,
+			Atomic:       v.Atomic,
+			Cache:        v.cacheFor(en),
 		}
+		en.status, en.err = WriteFileEx(en.FilePath, en.Payload.Bytes(), &opts)
+	})
+	if err != nil {
+		return err
 	}
 	return v.Errors()
 }
 
-// WritePending writes out all pending entries.
-func (v WriteFileset) WritePending() error {
-	if err := v.Errors(); err != nil {
-		return err
+func (v WriteFileset) cacheFor(en *WriteFileEntry) *MatchCache {
+	if en.Cache != nil {
+		return en.Cache
 	}
-	for _, en := range v.Entries {
-		if en.status == Creating || en.status == Overwriting {
-			opts := WriteOptions{
-				Perm:       en.Perm,
-				Backup:     en.Backup,
-				OnFeedback: v.OnFeedback,
+	return v.Cache
+}
+
+type feedbackEvent struct {
+	fb WriteFeedback
+	fn string
+}
+
+// forEach calls proc for each entry, using up to v.Workers goroutines. The
+// feedback that proc reports is buffered and delivered to v.OnFeedback in
+// entry order, from the calling goroutine. Stops dispatching entries when ctx
+// is cancelled.
+func (v WriteFileset) forEach(ctx context.Context, entries []*WriteFileEntry, proc func(en *WriteFileEntry, fb WriteFeedbackProc)) error {
+	if v.Workers <= 1 || len(entries) <= 1 {
+		for _, en := range entries {
+			if err := ctx.Err(); err != nil {
+				return err
 			}
-			en.status, en.err = WriteFileEx(en.FilePath, en.Payload.Bytes(), &opts)
+			proc(en, v.OnFeedback)
 		}
+		return nil
 	}
-	return v.Errors()
+
+	type result struct {
+		index  int
+		events []feedbackEvent
+	}
+	indices := make(chan int)
+	results := make(chan result)
+
+	workers := v.Workers
+	if workers > len(entries) {
+		workers = len(entries)
+	}
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				var events []feedbackEvent
+				var fb WriteFeedbackProc
+				if v.OnFeedback != nil {
+					fb = func(f WriteFeedback, fn string) {
+						events = append(events, feedbackEvent{f, fn})
+					}
+				}
+				proc(entries[i], fb)
+				results <- result{i, events}
+			}
+		}()
+	}
+	go func() {
+		defer close(indices)
+		for i := range entries {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// entries are dispatched in order, so completed ones always form a
+	// contiguous range once all results are in
+	done := map[int][]feedbackEvent{}
+	next := 0
+	for r := range results {
+		done[r.index] = r.events
+		for {
+			events, ok := done[next]
+			if !ok {
+				break
+			}
+			delete(done, next)
+			next++
+			for _, e := range events {
+				v.OnFeedback(e.fb, e.fn)
+			}
+		}
+	}
+	if next < len(entries) {
+		return ctx.Err()
+	}
+	return nil
 }
@@ -0,0 +1,494 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFilesetTransactional(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("old a"), 0666)
+
+	set := WriteFileset{Transactional: true}
+	set.Add("", a, bytes.NewBufferString("new a"))
+	set.Add("", b, bytes.NewBufferString("new b"))
+	if err := set.UpdateStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.WritePending(); err != nil {
+		t.Fatal(err)
+	}
+	for _, en := range set.Entries {
+		if en.Status() != Succeeded {
+			t.Errorf("%s: status %d", en.FilePath, en.Status())
+		}
+	}
+	if got, _ := os.ReadFile(a); string(got) != "new a" {
+		t.Errorf("a: got %q", got)
+	}
+	if got, _ := os.ReadFile(b); string(got) != "new b" {
+		t.Errorf("b: got %q", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("unexpected leftover files: %d entries", len(entries))
+	}
+}
+
+func TestWriteFilesetTransactionalStageFailure(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	os.WriteFile(a, []byte("old a"), 0666)
+
+	set := WriteFileset{Transactional: true}
+	set.Add("", a, bytes.NewBufferString("new a"))
+	set.Add("", filepath.Join(dir, "missing", "b.txt"), bytes.NewBufferString("new b"))
+	set.UpdateStatus()
+	set.Entries[1].status = Creating // pretend the directory exists
+	if err := set.WritePending(); err == nil {
+		t.Fatal("expected an error")
+	}
+	if set.Entries[0].Status() != Overwriting || set.Entries[1].Status() != Failed {
+		t.Errorf("statuses: %d, %d", set.Entries[0].Status(), set.Entries[1].Status())
+	}
+	if got, _ := os.ReadFile(a); string(got) != "old a" {
+		t.Errorf("a: got %q", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("unexpected leftover files: %d entries", len(entries))
+	}
+}
+
+func TestWriteFilesetRollback(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("old a"), 0666)
+
+	set := WriteFileset{}
+	ea := set.Add("", a, bytes.NewBufferString("new a"))
+	eb := set.Add("", b, bytes.NewBufferString("new b"))
+
+	orig, err := preserveOriginal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFileAtomic(a, []byte("new a"), 0666)
+	writeFileAtomic(b, []byte("new b"), 0666)
+
+	var events []WriteFeedback
+	set.OnFeedback = func(fb WriteFeedback, fn string) { events = append(events, fb) }
+	set.rollback([]*stagedEntry{
+		{en: ea, target: a, original: orig},
+		{en: eb, target: b},
+	})
+
+	if ea.Status() != RolledBack || eb.Status() != RolledBack {
+		t.Errorf("statuses: %d, %d", ea.Status(), eb.Status())
+	}
+	if got, _ := os.ReadFile(a); string(got) != "old a" {
+		t.Errorf("a: got %q", got)
+	}
+	if FileExists(b) {
+		t.Error("b should have been removed")
+	}
+	if len(events) != 4 || events[0] != FeedbackRollbackBegin || events[3] != FeedbackRollbackSucceded {
+		t.Errorf("feedback: %v", events)
+	}
+}
+
+func TestWriteFilesetPreview(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.bin")
+	os.WriteFile(a, []byte("one\ntwo\n"), 0666)
+
+	set := WriteFileset{}
+	set.Add("", a, bytes.NewBufferString("one\n2\n"))
+	set.Add("", b, bytes.NewBuffer([]byte{0, 1, 2}))
+	set.UpdateStatus()
+
+	buf := bytes.Buffer{}
+	if err := set.Preview(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "--- " + a + "\n+++ " + a + "\n@@ -1,2 +1,2 @@\n one\n-two\n+2\n" +
+		"Binary file " + b + ": new, " + ByteSizeStr(3) + " (sha256 ae4b3280e56e)\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if FileExists(b) {
+		t.Error("preview must not write files")
+	}
+}
+
+func TestWriteFilesetPreviewRewrite(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "a.txt")
+	a, b := strings.Builder{}, strings.Builder{}
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+	os.WriteFile(fn, []byte(a.String()), 0666)
+
+	en := NewWriteFileEntry("", fn, bytes.NewBufferString(b.String()))
+	en.UpdateStatus()
+	got, err := en.Preview(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "File (too many changes for a diff) "+fn+": ") || strings.Count(got, "\n") != 1 {
+		t.Errorf("expected a summary, got %d lines", strings.Count(got, "\n"))
+	}
+}
+
+func TestWriteFilesetStale(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "sub"), 0777)
+	os.WriteFile(filepath.Join(dir, "sub", "old.txt"), []byte("old"), 0666)
+	os.WriteFile(filepath.Join(dir, "keep.md"), []byte("keep"), 0666)
+
+	set := WriteFileset{}
+	set.Own(dir, "*.md")
+	set.Add("", filepath.Join(dir, "new.txt"), bytes.NewBufferString("new"))
+	if err := set.UpdateStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if stale := set.Roots[0].Stale(); len(stale) != 1 || stale[0] != filepath.Join(dir, "sub", "old.txt") {
+		t.Fatalf("stale: %v", stale)
+	}
+	if err := set.WritePending(); err != nil {
+		t.Fatal(err)
+	}
+	if DirExists(filepath.Join(dir, "sub")) {
+		t.Error("empty directory should have been removed")
+	}
+	if !FileExists(filepath.Join(dir, "keep.md")) || !FileExists(filepath.Join(dir, "new.txt")) {
+		t.Error("owned and excluded files must be kept")
+	}
+}
+
+func TestWriteFilesetStaleKeepsBackups(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	cache_fn := filepath.Join(dir, ".cache.json")
+
+	write := func() *WriteFileset {
+		set := &WriteFileset{Cache: OpenMatchCache(cache_fn)}
+		set.Own(dir)
+		set.Add("", a, bytes.NewBufferString(time.Now().String())).Backup = BackupNameNumeric(".bak", 10)
+		set.Add("", b, bytes.NewBufferString(time.Now().String())).BackupPolicy = &BackupPolicy{Dir: "backups"}
+		if err := set.UpdateStatus(); err != nil {
+			t.Fatal(err)
+		}
+		if err := set.WritePending(); err != nil {
+			t.Fatal(err)
+		}
+		if err := set.Cache.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return set
+	}
+	write()
+	write()
+	set := write()
+	if stale := set.Roots[0].Stale(); len(stale) != 0 {
+		t.Errorf("stale: %v", stale)
+	}
+	for _, fn := range []string{
+		filepath.Join(dir, "a.bak.txt"),
+		filepath.Join(dir, "a.bak_2.txt"),
+		filepath.Join(dir, "backups", "b.txt"),
+		filepath.Join(dir, "backups", "b_2.txt"),
+		cache_fn,
+	} {
+		if !FileExists(fn) {
+			t.Errorf("%s was removed", fn)
+		}
+	}
+}
+
+func TestWriteFilesetStaleKeepsGeneratorBackups(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(filepath.Join(dir, "b.bak_3.txt"), []byte("after a gap"), 0666)
+	os.WriteFile(filepath.Join(dir, "a.bakx.txt"), []byte("stale"), 0666)
+
+	var set *WriteFileset
+	for i := 0; i < 3; i++ {
+		set = &WriteFileset{}
+		set.Own(dir)
+		set.Add("", a, bytes.NewBufferString(fmt.Sprint(i))).Backup = BackupNameTimestamp(".bak", "20060102150405.000000000", 10)
+		set.Add("", b, bytes.NewBufferString(fmt.Sprint(i))).Backup = BackupNameNumeric(".bak", 10)
+		if err := set.UpdateStatus(); err != nil {
+			t.Fatal(err)
+		}
+		if err := set.WritePending(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(set.Roots[0].Stale()) != 0 || FileExists(filepath.Join(dir, "a.bakx.txt")) {
+		t.Error("files that do not match the backup naming must be removed")
+	}
+	a_backups, _ := filepath.Glob(filepath.Join(dir, "a.bak2*.txt"))
+	if len(a_backups) != 2 {
+		t.Errorf("timestamped backups: %v", a_backups)
+	}
+	if !FileExists(filepath.Join(dir, "b.bak_3.txt")) {
+		t.Error("numbered backup after a gap was removed")
+	}
+}
+
+func TestRemoveEmptyParents(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "out")
+	os.MkdirAll(filepath.Join(root, "sub"), 0777)
+	os.MkdirAll(filepath.Join(dir, "out2", "sub"), 0777)
+
+	removeEmptyParents(filepath.Join(dir, "out2", "sub", "x.txt"), root)
+	if !DirExists(filepath.Join(dir, "out2", "sub")) {
+		t.Error("removed a directory outside of the root")
+	}
+	removeEmptyParents(filepath.Join(root, "sub", "x.txt"), root)
+	if DirExists(filepath.Join(root, "sub")) || !DirExists(root) {
+		t.Error("expected the empty subdirectory removed and the root kept")
+	}
+}
+
+func TestWriteFilesetManifestSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges")
+	}
+	dir := t.TempDir()
+	outside := t.TempDir()
+	victim := filepath.Join(outside, "victim.txt")
+	os.WriteFile(victim, []byte("victim"), 0666)
+	os.Symlink(outside, filepath.Join(dir, "link"))
+	os.WriteFile(filepath.Join(dir, ".manifest"), []byte("link/victim.txt\n"), 0666)
+
+	set := WriteFileset{}
+	set.Own(dir).Manifest = ".manifest"
+	if err := set.UpdateStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if stale := set.Roots[0].Stale(); len(stale) != 0 {
+		t.Errorf("stale: %v", stale)
+	}
+	if err := set.WritePending(); err != nil {
+		t.Fatal(err)
+	}
+	if !FileExists(victim) {
+		t.Error("removed a file outside of the root")
+	}
+}
+
+func TestWriteFilesetManifest(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	user := filepath.Join(dir, "user.txt")
+	os.WriteFile(user, []byte("user"), 0666)
+
+	write := func(files ...string) {
+		set := WriteFileset{}
+		set.Own(dir).Manifest = ".manifest"
+		for _, fn := range files {
+			set.Add("", fn, bytes.NewBufferString(fn))
+		}
+		if err := set.UpdateStatus(); err != nil {
+			t.Fatal(err)
+		}
+		if err := set.WritePending(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(a, b)
+	if got, _ := os.ReadFile(filepath.Join(dir, ".manifest")); !bytes.HasSuffix(got, []byte("\na.txt\nb.txt\n")) {
+		t.Errorf("manifest: %q", got)
+	}
+	write(a)
+	if FileExists(b) {
+		t.Error("b should have been removed")
+	}
+	if !FileExists(user) {
+		t.Error("files not listed in the manifest must be kept")
+	}
+}
+
+func TestWriteFilesetWorkers(t *testing.T) {
+	dir := t.TempDir()
+	var got, want []string
+	set := WriteFileset{Workers: 4}
+	set.OnFeedback = func(fb WriteFeedback, fn string) {
+		if fb == FeedbackWriteSucceded {
+			got = append(got, fn)
+		}
+	}
+	for i := 0; i < 50; i++ {
+		fn := filepath.Join(dir, fmt.Sprintf("%02d.txt", i))
+		set.Add("", fn, bytes.NewBufferString(fn))
+		want = append(want, fn)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := set.UpdateStatusContext(ctx); err != context.Canceled {
+		t.Fatalf("cancelled: got %v", err)
+	}
+
+	if err := set.UpdateStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if n := set.Count(Creating); n != 50 {
+		t.Fatalf("pending: %d", n)
+	}
+	if err := set.WritePending(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("feedback order:\n%v\nwant:\n%v", got, want)
+	}
+	if n := set.Count(Succeeded); n != 50 {
+		t.Errorf("succeeded: %d", n)
+	}
+}
+
+func TestWriteFilesetTransactionalSwapFailure(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	c := filepath.Join(dir, "c.txt")
+	os.WriteFile(a, []byte("old a"), 0666)
+	os.WriteFile(b, []byte("old b"), 0666)
+
+	defer func() { renameFile = os.Rename }()
+	renameFile = func(from, to string) error {
+		if to == c {
+			return os.ErrPermission
+		}
+		return os.Rename(from, to)
+	}
+
+	var events []WriteFeedback
+	set := WriteFileset{Transactional: true}
+	set.OnFeedback = func(fb WriteFeedback, fn string) { events = append(events, fb) }
+	set.Add("", a, bytes.NewBufferString("new a"))
+	set.Add("", b, bytes.NewBufferString("new b")).Backup = BackupNameNumeric(".bak", 10)
+	set.Add("", c, bytes.NewBufferString("new c"))
+	set.UpdateStatus()
+	if err := set.WritePending(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	want := []WriteFileStatus{RolledBack, RolledBack, Failed}
+	for i, en := range set.Entries {
+		if en.Status() != want[i] {
+			t.Errorf("%s: status %d, want %d", en.FilePath, en.Status(), want[i])
+		}
+	}
+	if got, _ := os.ReadFile(a); string(got) != "old a" {
+		t.Errorf("a: got %q", got)
+	}
+	if got, _ := os.ReadFile(b); string(got) != "old b" {
+		t.Errorf("b: got %q", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "b.bak.txt")); string(got) != "old b" {
+		t.Errorf("backup must be kept: got %q", got)
+	}
+	if FileExists(c) {
+		t.Error("c must not be created")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Errorf("unexpected leftover files: %d entries", len(entries))
+	}
+	n := 0
+	for _, fb := range events {
+		if fb == FeedbackRollbackSucceded {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Errorf("feedback: %v", events)
+	}
+}
+
+func TestWriteFilesetTransactionalStageFailureKeepsBackups(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	os.WriteFile(a, []byte("old a"), 0666)
+
+	set := WriteFileset{Transactional: true}
+	set.Add("", a, bytes.NewBufferString("new a")).Backup = BackupNameNumeric(".bak", 10)
+	set.Add("", filepath.Join(dir, "missing", "b.txt"), bytes.NewBufferString("new b"))
+	set.UpdateStatus()
+	set.Entries[1].status = Creating
+	if err := set.WritePending(); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "a.bak.txt")); string(got) != "old a" {
+		t.Errorf("reported backup was discarded: %q", got)
+	}
+}
+
+func TestWriteFilesetWorkersSharedBackupDir(t *testing.T) {
+	for _, atomic := range []bool{false, true} {
+		dir := t.TempDir()
+		backups := filepath.Join(dir, "backups")
+		const n = 16
+		set := WriteFileset{Workers: 8, Atomic: atomic}
+		for i := 0; i < n; i++ {
+			fn := filepath.Join(dir, fmt.Sprint(i), "x.txt")
+			os.MkdirAll(filepath.Dir(fn), 0777)
+			os.WriteFile(fn, []byte(fmt.Sprint("old ", i)), 0666)
+			set.Add("", fn, bytes.NewBufferString(fmt.Sprint("new ", i))).Backup = BackupNameInDir(backups, BackupNameNumeric(".bak", 2*n))
+		}
+		if err := set.UpdateStatus(); err != nil {
+			t.Fatal(err)
+		}
+		if err := set.WritePending(); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, _ := os.ReadDir(backups)
+		seen := map[string]bool{}
+		for _, e := range entries {
+			b, _ := os.ReadFile(filepath.Join(backups, e.Name()))
+			seen[string(b)] = true
+		}
+		if len(entries) != n || len(seen) != n {
+			t.Errorf("atomic %v: %d backups, %d distinct", atomic, len(entries), len(seen))
+		}
+	}
+}
+
+func TestWriteFilesetTransactionalBackupDirFailure(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	os.WriteFile(a, []byte("old a"), 0666)
+	os.WriteFile(filepath.Join(dir, "file"), nil, 0666)
+
+	set := WriteFileset{Transactional: true}
+	set.Add("", filepath.Join(dir, "b.txt"), bytes.NewBufferString("new b"))
+	set.Add("", a, bytes.NewBufferString("new a")).Backup = BackupNameInDir(filepath.Join(dir, "file", "backups"), BackupNameNumeric(".bak", 10))
+	set.UpdateStatus()
+	if err := set.WritePending(); err == nil {
+		t.Fatal("expected an error")
+	}
+	if set.Entries[1].Status() != Failed || set.Entries[1].LastError() == nil {
+		t.Errorf("status %d, err %v", set.Entries[1].Status(), set.Entries[1].LastError())
+	}
+	if got, _ := os.ReadFile(a); string(got) != "old a" || FileExists(filepath.Join(dir, "b.txt")) {
+		t.Error("nothing must be written when a backup directory can not be created")
+	}
+}
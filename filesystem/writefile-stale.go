@@ -0,0 +1,290 @@
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OutputRoot is a directory owned by a WriteFileset. Files under it that are
+// not produced by the set are considered stale: UpdateStatus detects them,
+// Preview reports them, and WritePending removes them once all the entries
+// are written out successfully.
+//
+// When Manifest is specified, only the files recorded in it (produced by the
+// set last time) are considered for removal and the manifest is updated after
+// each successful WritePending. Otherwise, all files under Dir that are not in
+// the set are considered for removal, except for the backups of the entries
+// and the match cache files.
+type OutputRoot struct {
+	Dir      string   // root directory
+	Exclude  []string // glob patterns for files that are never stale (see MatchExclude)
+	Manifest string   // optional manifest filename, relative to Dir
+
+	stale []string
+}
+
+// Own makes the set responsible for files under the specified directory.
+func (v *WriteFileset) Own(dir string, exclude ...string) *OutputRoot {
+	r := &OutputRoot{Dir: dir, Exclude: exclude}
+	v.Roots = append(v.Roots, r)
+	return r
+}
+
+// Stale returns the list of stale files detected by the last UpdateStatus
+// call.
+func (r *OutputRoot) Stale() []string {
+	return r.stale
+}
+
+// MatchExclude checks if the slash-separated path relative to the root
+// matches any of the exclusion patterns (see MatchGlob).
+func (r *OutputRoot) MatchExclude(rel string) bool {
+	for _, p := range r.Exclude {
+		if MatchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// rel converts fn into a slash-separated path relative to the root, fails if
+// fn is outside of the root.
+func (r *OutputRoot) rel(root string, fn string) (string, bool) {
+	abs, err := filepath.Abs(fn)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// owned collects the slash-separated relative paths of the entries under the
+// root.
+func (r *OutputRoot) owned(root string, entries []*WriteFileEntry) map[string]bool {
+	owned := map[string]bool{}
+	for _, en := range entries {
+		if rel, ok := r.rel(root, en.FilePath); ok {
+			owned[rel] = true
+		}
+	}
+	return owned
+}
+
+// protect adds the backups of the entries and the cache files to owned. Backups
+// made with a plain BackupNameGenerator are recognized by their names, see
+// backupMatcher.
+func (r *OutputRoot) protect(root string, owned map[string]bool, entries []*WriteFileEntry, cache *MatchCache) {
+	add := func(fn string) {
+		if rel, ok := r.rel(root, fn); ok {
+			owned[rel] = true
+		}
+	}
+	if cache != nil {
+		add(cache.fn)
+	}
+	for _, en := range entries {
+		if en.Cache != nil {
+			add(en.Cache.fn)
+		}
+		if en.BackupPolicy != nil {
+			list, _ := en.BackupPolicy.List(en.FilePath)
+			for _, b := range list {
+				add(b.Path)
+			}
+		} else if en.Backup != nil {
+			dir, match := backupMatcher(en.FilePath, en.Backup)
+			if dir == "" {
+				continue
+			}
+			list, _ := os.ReadDir(dir)
+			for _, e := range list {
+				if !e.IsDir() && match(e.Name()) {
+					add(filepath.Join(dir, e.Name()))
+				}
+			}
+		}
+	}
+}
+
+// contains checks that fn is located under the root after resolving symlinks
+// in its parent directories.
+func contains(real_root string, fn string) bool {
+	dir, err := filepath.EvalSymlinks(filepath.Dir(fn))
+	return err == nil && isInside(dir, real_root)
+}
+
+func (r *OutputRoot) manifestPath() string {
+	return filepath.Join(r.Dir, filepath.FromSlash(r.Manifest))
+}
+
+// readManifest loads the list of files recorded by the previous run, a
+// missing manifest is treated as empty.
+func (r *OutputRoot) readManifest() ([]string, error) {
+	buf, err := os.ReadFile(r.manifestPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var list []string
+	for _, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			list = append(list, line)
+		}
+	}
+	return list, nil
+}
+
+// update detects stale files.
+func (r *OutputRoot) update(entries []*WriteFileEntry, cache *MatchCache) error {
+	r.stale = nil
+	root, err := filepath.Abs(r.Dir)
+	if err != nil {
+		return err
+	}
+	owned := r.owned(root, entries)
+	if r.Manifest != "" {
+		owned[path.Clean(filepath.ToSlash(r.Manifest))] = true
+		list, err := r.readManifest()
+		if err != nil {
+			return err
+		}
+		real_root, err := filepath.EvalSymlinks(root)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		for _, rel := range list {
+			rel = path.Clean(rel)
+			if owned[rel] || r.MatchExclude(rel) || rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+				continue
+			}
+			fn := filepath.Join(r.Dir, filepath.FromSlash(rel))
+			if !contains(real_root, fn) {
+				continue
+			}
+			if info, err := os.Lstat(fn); err == nil && !info.IsDir() {
+				r.stale = append(r.stale, fn)
+			}
+		}
+	} else {
+		r.protect(root, owned, entries, cache)
+		err = filepath.WalkDir(root, func(fn string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if fn == root && errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if fn == root {
+				return nil
+			}
+			rel, _ := r.rel(root, fn)
+			if d.IsDir() {
+				if r.MatchExclude(rel) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !owned[rel] && !r.MatchExclude(rel) {
+				r.stale = append(r.stale, filepath.Join(r.Dir, filepath.FromSlash(rel)))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	sort.Strings(r.stale)
+	return nil
+}
+
+// removeEmptyParents removes directories that became empty after removing
+// fn, stops at the root.
+func removeEmptyParents(fn string, root string) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return
+	}
+	dir, err := filepath.Abs(filepath.Dir(fn))
+	for err == nil && dir != root && isInside(dir, root) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// cleanup removes stale files and updates manifests.
+func (v WriteFileset) cleanup() error {
+	var first_err error
+	for _, r := range v.Roots {
+		var remaining []string
+		for _, fn := range r.stale {
+			v.feedback(FeedbackRemoveBegin, fn)
+			if err := os.Remove(fn); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				v.feedback(FeedbackRemoveFailed, fn)
+				remaining = append(remaining, fn)
+				if first_err == nil {
+					first_err = err
+				}
+				continue
+			}
+			v.feedback(FeedbackRemoveSucceded, fn)
+			v.Cache.Forget(fn)
+			removeEmptyParents(fn, r.Dir)
+		}
+		r.stale = remaining
+
+		if r.Manifest == "" {
+			continue
+		}
+		root, err := filepath.Abs(r.Dir)
+		if err != nil {
+			return err
+		}
+		owned := r.owned(root, v.Entries)
+		delete(owned, path.Clean(filepath.ToSlash(r.Manifest)))
+		for _, fn := range remaining {
+			// keep failed removals for the next attempt
+			if rel, ok := r.rel(root, fn); ok {
+				owned[rel] = true
+			}
+		}
+		list := make([]string, 0, len(owned))
+		for rel := range owned {
+			list = append(list, rel)
+		}
+		sort.Strings(list)
+
+		buf := bytes.Buffer{}
+		buf.WriteString("# files produced by the fileset, used for stale file cleanup\n")
+		for _, rel := range list {
+			buf.WriteString(rel)
+			buf.WriteByte('\n')
+		}
+		_, err = WriteFileEx(r.manifestPath(), buf.Bytes(), &WriteOptions{OnFeedback: v.OnFeedback})
+		if err != nil && first_err == nil {
+			first_err = err
+		}
+	}
+	return first_err
+}
+
+// CountStale counts stale files under the owned roots.
+func (v WriteFileset) CountStale() int {
+	n := 0
+	for _, r := range v.Roots {
+		n += len(r.stale)
+	}
+	return n
+}
@@ -0,0 +1,178 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// renameFile is replaced in tests to simulate failures
+var renameFile = os.Rename
+
+// stagedEntry tracks a single entry during a transactional write.
+type stagedEntry struct {
+	en       *WriteFileEntry
+	target   string // resolved file path
+	staged   string // temporary file with the new content
+	original string // preserved original content (backup or hidden temporary file), empty when creating
+	isBackup bool   // original is a user-requested backup
+}
+
+func (v WriteFileset) feedback(fb WriteFeedback, fn string) {
+	if v.OnFeedback != nil {
+		v.OnFeedback(fb, fn)
+	}
+}
+
+// commit writes out the entries in an all-or-nothing fashion:
+//
+//   - stage: new content is written into temporary files next to the targets,
+//     originals are preserved (as backups, if requested)
+//   - swap: temporary files are renamed over the targets
+//   - rollback: if any of the swaps fails, entries that were already swapped
+//     are restored from the preserved originals (or removed, if they were
+//     just created) and get the RolledBack status
+//
+// When staging fails or ctx is cancelled while staging, nothing is written
+// and the entries that were not attempted retain their pending status.
+// Backups that were made are kept in all cases.
+func (v WriteFileset) commit(ctx context.Context, pending []*WriteFileEntry) error {
+	staged := make([]*stagedEntry, 0, len(pending))
+
+	fail := func(en *WriteFileEntry, err error) {
+		en.status = Failed
+		en.err = err
+	}
+
+	discard := func(list []*stagedEntry) {
+		for _, st := range list {
+			if st.staged != "" {
+				os.Remove(st.staged)
+			}
+			if st.original != "" && !st.isBackup {
+				os.Remove(st.original)
+			}
+		}
+	}
+
+	// stage
+	for _, en := range pending {
+		if err := ctx.Err(); err != nil {
+			discard(staged)
+			return err
+		}
+		st := &stagedEntry{en: en, target: resolveTarget(en.FilePath)}
+		perm := en.Perm
+		if perm == 0 {
+			perm = 0666
+		}
+		var err error
+		st.staged, err = stageFile(st.target, en.Payload.Bytes(), perm)
+		if err != nil {
+			v.feedback(FeedbackWriteBegin, en.FilePath)
+			v.feedback(FeedbackWriteFailed, en.FilePath)
+			fail(en, err)
+			discard(staged)
+			return nil
+		}
+		staged = append(staged, st)
+
+		if _, err = os.Stat(st.target); err != nil {
+			continue // creating
+		}
+		if backup := backupGenerator(en.Backup, en.BackupPolicy); backup != nil {
+			backup_fn, err := makeBackup(en.FilePath, backup, func(backup_fn string) error {
+				return linkOrCopy(st.target, backup_fn)
+			})
+			if backup_fn != "" {
+				v.feedback(FeedbackBackupBegin, backup_fn)
+				if err == nil {
+					v.feedback(FeedbackBackupSucceded, backup_fn)
+					st.original, st.isBackup = backup_fn, true
+				} else {
+					v.feedback(FeedbackBackupFailed, backup_fn)
+				}
+			}
+			if err != nil {
+				fail(en, err)
+				discard(staged)
+				return nil
+			}
+		} else if st.original, err = preserveOriginal(st.target); err != nil {
+			v.feedback(FeedbackWriteBegin, en.FilePath)
+			v.feedback(FeedbackWriteFailed, en.FilePath)
+			fail(en, err)
+			discard(staged)
+			return nil
+		}
+	}
+
+	// swap
+	for i, st := range staged {
+		v.feedback(FeedbackWriteBegin, st.en.FilePath)
+		if err := renameFile(st.staged, st.target); err != nil {
+			v.feedback(FeedbackWriteFailed, st.en.FilePath)
+			fail(st.en, err)
+			discard(staged[i:])
+			v.rollback(staged[:i])
+			return nil
+		}
+		st.staged = ""
+		st.en.status = Succeeded
+		st.en.err = nil
+		v.cacheFor(st.en).Record(st.target, st.en.Payload.Bytes())
+		v.feedback(FeedbackWriteSucceded, st.en.FilePath)
+	}
+
+	// best effort: persist the renames
+	synced := map[string]bool{}
+	for _, st := range staged {
+		dir := filepath.Dir(st.target)
+		if !synced[dir] {
+			synced[dir] = true
+			syncDir(dir)
+		}
+		if st.original != "" && !st.isBackup {
+			os.Remove(st.original)
+		}
+		if st.isBackup {
+			pruneBackups(st.en.FilePath, st.original, st.en.BackupPolicy, v.OnFeedback)
+		}
+	}
+	return nil
+}
+
+// rollback restores swapped entries in reverse order.
+func (v WriteFileset) rollback(list []*stagedEntry) {
+	for i := len(list) - 1; i >= 0; i-- {
+		st := list[i]
+		v.cacheFor(st.en).Forget(st.target)
+		v.feedback(FeedbackRollbackBegin, st.en.FilePath)
+		var err error
+		if st.isBackup {
+			// keep the backup, restore a copy
+			err = copyOver(st.original, st.target)
+			if err != nil {
+				v.feedback(FeedbackBackupRestoreFailed, st.original)
+			}
+		} else if st.original != "" {
+			err = os.Rename(st.original, st.target)
+			if err != nil {
+				v.feedback(FeedbackBackupRestoreFailed, st.original)
+			}
+		} else {
+			err = os.Remove(st.target)
+			if err != nil {
+				v.feedback(FeedbackRollbackFailed, st.en.FilePath)
+			}
+		}
+		if err != nil {
+			st.en.status = Failed
+			st.en.err = err
+			continue
+		}
+		st.en.status = RolledBack
+		st.en.err = nil
+		v.feedback(FeedbackRollbackSucceded, st.en.FilePath)
+	}
+}
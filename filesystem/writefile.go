@@ -10,8 +10,11 @@ import (
 type WriteOptions struct {
 	Perm                     os.FileMode         // file writing permissions, defaults to 0666 if unspecidied (perm == 0)
 	OverwriteMatchingContent bool                // backup and overwrite, even if content matches
+	Atomic                   bool                // write into a temporary file, then rename it over the original
 	Backup                   BackupNameGenerator // backup filename generator, no backup by default
+	BackupPolicy             *BackupPolicy       // overrides Backup, prunes old backups after writing
 	OnFeedback               WriteFeedbackProc   // use this if logging or user feedback is required
+	Cache                    *MatchCache         // optional content hash cache for detecting matching content
 }
 
 // WriteFile writes data to the named file with configurable behavior and
@@ -33,10 +36,17 @@ const (
 	Failed
 	Skipped
 	Succeeded
+	RolledBack
 )
 
 // WriteFileEx writes data to the named file with configurable behavior and
 // feedback, provides detailed status.
+//
+// In atomic mode (see WriteOptions.Atomic), the data is written into a
+// temporary file in the same directory, flushed to disk, and renamed over the
+// original, which keeps its permissions and ownership. Backups are made by
+// linking or copying the original, so that it stays in place until the rename;
+// the backup is kept if writing fails.
 func WriteFileEx(fn string, buf []byte, opts *WriteOptions) (status WriteFileStatus, err error) {
 	if opts == nil {
 		err = os.WriteFile(fn, buf, 0666)
@@ -58,9 +68,14 @@ func WriteFileEx(fn string, buf []byte, opts *WriteOptions) (status WriteFileSta
 		if opts.OnFeedback != nil {
 			opts.OnFeedback(FeedbackWriteBegin, fn)
 		}
-		err = os.WriteFile(fn, buf, perm)
+		if opts.Atomic {
+			err = writeFileAtomic(fn, buf, perm)
+		} else {
+			err = os.WriteFile(fn, buf, perm)
+		}
 		if err == nil {
 			status = Succeeded
+			opts.Cache.Record(fn, buf)
 			if opts.OnFeedback != nil {
 				opts.OnFeedback(FeedbackWriteSucceded, fn)
 			}
@@ -74,7 +89,7 @@ func WriteFileEx(fn string, buf []byte, opts *WriteOptions) (status WriteFileSta
 
 	if !opts.OverwriteMatchingContent {
 		var match bool
-		match, err = FileContentMatch(fn, buf)
+		match, err = opts.Cache.Match(fn, buf)
 		if err != nil {
 			if errors.Is(err, fs.ErrNotExist) {
 				// creating new
@@ -99,33 +114,28 @@ func WriteFileEx(fn string, buf []byte, opts *WriteOptions) (status WriteFileSta
 		}
 	}
 
-	if opts.Backup == nil {
+	backup := backupGenerator(opts.Backup, opts.BackupPolicy)
+	if backup == nil {
 		perform_write()
 		return
 	}
 
-	backup_attempt := 1
-	backup_fn := opts.Backup(fn, backup_attempt)
+	backup_fn, err := makeBackup(fn, backup, func(backup_fn string) error {
+		if opts.Atomic {
+			// writeFileAtomic replaces the symlink target, back it up instead
+			// of the link itself
+			return linkOrCopy(resolveTarget(fn), backup_fn)
+		}
+		return renameExclusive(fn, backup_fn)
+	})
 	if backup_fn == "" {
 		status = Failed
-		err = errFileBackupFailed
 		return
 	}
-
-	for FileExists(backup_fn) {
-		backup_attempt++
-		backup_fn = opts.Backup(fn, backup_attempt)
-		if backup_fn == "" {
-			status = Failed
-			err = errFileBackupFailed
-			return
-		}
-	}
-
 	if opts.OnFeedback != nil {
 		opts.OnFeedback(FeedbackBackupBegin, backup_fn)
 	}
-	if err = os.Rename(fn, backup_fn); err != nil {
+	if err != nil {
 		status = Failed
 		if opts.OnFeedback != nil {
 			opts.OnFeedback(FeedbackBackupFailed, backup_fn)
@@ -137,12 +147,16 @@ func WriteFileEx(fn string, buf []byte, opts *WriteOptions) (status WriteFileSta
 	}
 
 	perform_write()
-	if err != nil {
+	if err != nil && opts.Atomic {
+		// the original is intact, the backup that was reported is kept
+	} else if err != nil {
 		// try to restore backup
 		restore_err := os.Rename(backup_fn, fn)
 		if restore_err != nil && opts.OnFeedback != nil {
 			opts.OnFeedback(FeedbackBackupRestoreFailed, backup_fn)
 		}
+	} else {
+		pruneBackups(fn, backup_fn, opts.BackupPolicy, opts.OnFeedback)
 	}
 	return
 }
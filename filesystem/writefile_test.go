@@ -0,0 +1,414 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWriteFileExAtomic(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "out.txt")
+
+	var events []WriteFeedback
+	opts := &WriteOptions{
+		Atomic:     true,
+		Backup:     BackupNameNumeric(".bak", 10),
+		OnFeedback: func(fb WriteFeedback, fn string) { events = append(events, fb) },
+	}
+
+	status, err := WriteFileEx(fn, []byte("one"), opts)
+	if err != nil || status != Succeeded {
+		t.Fatalf("create: status %d, err %v", status, err)
+	}
+	if err = os.Chmod(fn, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	events = nil
+	status, err = WriteFileEx(fn, []byte("two"), opts)
+	if err != nil || status != Succeeded {
+		t.Fatalf("overwrite: status %d, err %v", status, err)
+	}
+	want := []WriteFeedback{FeedbackBackupBegin, FeedbackBackupSucceded, FeedbackWriteBegin, FeedbackWriteSucceded}
+	if len(events) != len(want) {
+		t.Fatalf("feedback: got %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("feedback: got %v, want %v", events, want)
+		}
+	}
+
+	if b, _ := os.ReadFile(fn); string(b) != "two" {
+		t.Errorf("content: got %q", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "out.bak.txt")); string(b) != "one" {
+		t.Errorf("backup content: got %q", b)
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(fn); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("permissions were not preserved: %v", info.Mode())
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("unexpected leftover files: %d entries", len(entries))
+	}
+}
+
+func TestWriteFileExAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "missing", "out.txt")
+	status, err := WriteFileEx(fn, []byte("x"), &WriteOptions{Atomic: true})
+	if err == nil || status != Failed {
+		t.Errorf("expected failure, got status %d, err %v", status, err)
+	}
+}
+
+func TestWriteFileExBackupFailures(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "out.txt")
+	os.WriteFile(fn, []byte("old"), 0666)
+
+	var events []WriteFeedback
+	opts := &WriteOptions{
+		Atomic:     true,
+		OnFeedback: func(fb WriteFeedback, fn string) { events = append(events, fb) },
+	}
+
+	// backup directory can not be created
+	os.WriteFile(filepath.Join(dir, "file"), nil, 0666)
+	opts.Backup = BackupNameInDir(filepath.Join(dir, "file", "backups"), BackupNameNumeric(".bak", 10))
+	if status, err := WriteFileEx(fn, []byte("new"), opts); err == nil || status != Failed {
+		t.Errorf("backup directory: status %d, err %v", status, err)
+	}
+	if b, _ := os.ReadFile(fn); string(b) != "old" {
+		t.Errorf("original was overwritten: %q", b)
+	}
+
+	// the write fails after the backup was reported
+	events = nil
+	opts.Backup = BackupNameNumeric(".bak", 10)
+	defer func() { renameFile = os.Rename }()
+	renameFile = func(from, to string) error { return os.ErrPermission }
+	if _, err := WriteFileEx(fn, []byte("new"), opts); err == nil {
+		t.Error("expected a write error")
+	}
+	want := []WriteFeedback{FeedbackBackupBegin, FeedbackBackupSucceded, FeedbackWriteBegin, FeedbackWriteFailed}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("feedback: %v, want %v", events, want)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "out.bak.txt")); string(b) != "old" {
+		t.Errorf("reported backup was removed: %q", b)
+	}
+}
+
+func TestMatchCache(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "asset.bin")
+	cache_fn := filepath.Join(dir, "cache.json")
+	os.WriteFile(fn, []byte("abcd"), 0666)
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(fn, past, past)
+
+	cache := OpenMatchCache(cache_fn)
+	if match, err := cache.Match(fn, []byte("abcd")); err != nil || !match {
+		t.Fatalf("full comparison: %v, %v", match, err)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// modify the content in place, keeping size and timestamps: the reloaded
+	// cache must decide by metadata, without reading the file
+	os.WriteFile(fn, []byte("wxyz"), 0666)
+	os.Chtimes(fn, past, past)
+	cache = OpenMatchCache(cache_fn)
+	if match, _ := cache.Match(fn, []byte("abcd")); !match {
+		t.Error("expected a cache hit")
+	}
+	if match, _ := cache.Match(fn, []byte("abce")); match {
+		t.Error("expected a hash mismatch")
+	}
+
+	// recently modified files are ambiguous and compared in full
+	os.Chtimes(fn, time.Now(), time.Now())
+	if match, _ := cache.Match(fn, []byte("wxyz")); !match {
+		t.Error("expected a full comparison")
+	}
+	if _, err := cache.Match(filepath.Join(dir, "missing"), nil); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+
+	var nilCache *MatchCache
+	if match, _ := nilCache.Match(fn, []byte("wxyz")); !match {
+		t.Error("nil cache must compare in full")
+	}
+}
+
+func TestMatchCacheRecord(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "out.txt")
+	cache_fn := filepath.Join(dir, "cache.json")
+	defer func() { cacheNow = time.Now }()
+
+	if _, err := WriteFileEx(fn, []byte("abcd"), nil); err != nil {
+		t.Fatal(err)
+	}
+	cache := OpenMatchCache(cache_fn)
+	cache.Record(fn, []byte("abcd"))
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// next run, a minute later: the recorded entry is racy, gets confirmed
+	// and stored again
+	cacheNow = func() time.Time { return time.Now().Add(time.Minute) }
+	cache = OpenMatchCache(cache_fn)
+	if match, err := cache.Match(fn, []byte("abcd")); err != nil || !match {
+		t.Fatalf("racy entry: %v, %v", match, err)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// same size and timestamps, different content: only a cache hit can
+	// report a match
+	info, _ := os.Stat(fn)
+	os.WriteFile(fn, []byte("wxyz"), 0666)
+	os.Chtimes(fn, info.ModTime(), info.ModTime())
+	cache = OpenMatchCache(cache_fn)
+	if match, _ := cache.Match(fn, []byte("abcd")); !match {
+		t.Error("expected a cache hit")
+	}
+}
+
+func TestBackupPolicy(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "out.txt")
+	policy := &BackupPolicy{Suffix: ".bak", Dir: "backups", KeepLast: 2}
+
+	var pruned []string
+	opts := &WriteOptions{
+		BackupPolicy: policy,
+		OnFeedback: func(fb WriteFeedback, fn string) {
+			if fb == FeedbackBackupPruned {
+				pruned = append(pruned, fn)
+			}
+		},
+	}
+	for i := 1; i <= 4; i++ {
+		if _, err := WriteFileEx(fn, []byte{'0' + byte(i)}, opts); err != nil {
+			t.Fatal(err)
+		}
+		// distinct modification times for ordering
+		ts := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(fn, ts, ts)
+	}
+
+	list, err := policy.List(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || len(pruned) != 1 {
+		t.Fatalf("backups: %v, pruned: %v", list, pruned)
+	}
+	if filepath.Dir(list[0].Path) != filepath.Join(dir, "backups") {
+		t.Errorf("backup location: %s", list[0].Path)
+	}
+	for i, want := range []string{"3", "2"} {
+		if b, _ := os.ReadFile(list[i].Path); string(b) != want {
+			t.Errorf("backup #%d: got %q, want %q", i, b, want)
+		}
+	}
+
+	if _, err = policy.RestoreLatest(fn, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(fn); string(b) != "3" {
+		t.Errorf("restored: got %q", b)
+	}
+}
+
+func TestBackupPolicyKeepNewer(t *testing.T) {
+	for _, atomic := range []bool{false, true} {
+		dir := t.TempDir()
+		fn := filepath.Join(dir, "x.txt")
+		os.WriteFile(fn, []byte("old"), 0666)
+		past := time.Now().Add(-48 * time.Hour)
+		os.Chtimes(fn, past, past)
+
+		policy := &BackupPolicy{Suffix: ".bak", KeepNewer: 24 * time.Hour}
+		if _, err := WriteFileEx(fn, []byte("new"), &WriteOptions{BackupPolicy: policy, Atomic: atomic}); err != nil {
+			t.Fatal(err)
+		}
+		list, err := policy.List(fn)
+		if err != nil || len(list) != 1 {
+			t.Fatalf("atomic %v: backups %v, %v", atomic, list, err)
+		}
+		if time.Since(list[0].Time) > time.Hour {
+			t.Errorf("atomic %v: backup time %v", atomic, list[0].Time)
+		}
+		if b, _ := os.ReadFile(list[0].Path); string(b) != "old" {
+			t.Errorf("atomic %v: backup content %q", atomic, b)
+		}
+	}
+
+	// the new backup is kept even if it does not fit into the limits
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "x.txt")
+	os.WriteFile(fn, []byte("old content"), 0666)
+	policy := &BackupPolicy{Suffix: ".bak", MaxTotalSize: 1}
+	if _, err := WriteFileEx(fn, []byte("new"), &WriteOptions{BackupPolicy: policy}); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := policy.List(fn); len(list) != 1 {
+		t.Errorf("backups: %v", list)
+	}
+}
+
+func TestBackupPolicyDirWithoutSuffix(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "x.txt")
+	policy := &BackupPolicy{Dir: "backups"}
+	opts := &WriteOptions{BackupPolicy: policy}
+	for i := 1; i <= 3; i++ {
+		if _, err := WriteFileEx(fn, []byte{'0' + byte(i)}, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := policy.List(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("backups: %v", list)
+	}
+	found := false
+	for _, b := range list {
+		found = found || b.Path == filepath.Join(dir, "backups", "x.txt")
+	}
+	if !found {
+		t.Errorf("first backup is not listed: %v", list)
+	}
+}
+
+func TestBackupNameTimestamp(t *testing.T) {
+	gen := BackupNameTimestamp(".bak", "20060102", 3)
+	if a, b := gen("x.txt", 1), gen("x.txt", 2); a == b {
+		t.Errorf("attempts produced the same name %q", a)
+	}
+	if gen("x.txt", 4) != "" {
+		t.Error("expected exhausted attempts")
+	}
+
+	policy := &BackupPolicy{Suffix: ".bak", Timestamp: "20060102"}
+	for name, want := range map[string]bool{
+		"x.bak20240131.txt":   true,
+		"x.bak20240131_2.txt": true,
+		"x.bak.txt":           false,
+		"x.bakfoo.txt":        false,
+		"x.txt":               false,
+	} {
+		if _, ok := policy.parseName("x.txt", name, true); ok != want {
+			t.Errorf("parseName(%q): got %v", name, ok)
+		}
+	}
+}
+
+func TestBackupPolicyUnderscoreTimestamp(t *testing.T) {
+	policy := &BackupPolicy{Suffix: ".bak", Timestamp: "20060102_150405"}
+	want := time.Date(2024, 1, 31, 12, 30, 45, 0, time.Local)
+	for name, ok := range map[string]bool{
+		"x.bak20240131_123045.txt":   true,
+		"x.bak20240131_123045_2.txt": true,
+		"x.bak20240131.txt":          false,
+		"x.bak20240131_2.txt":        false,
+	} {
+		ts, got := policy.parseName("x.txt", name, true)
+		if got != ok {
+			t.Errorf("parseName(%q): got %v", name, got)
+		} else if ok && !ts.Equal(want) {
+			t.Errorf("parseName(%q): got %v, want %v", name, ts, want)
+		}
+	}
+
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "x.txt")
+	os.WriteFile(fn, []byte("0"), 0666)
+	for i := 1; i <= 2; i++ {
+		if _, err := WriteFileEx(fn, []byte{'0' + byte(i)}, &WriteOptions{BackupPolicy: policy}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if list, err := policy.List(fn); err != nil || len(list) != 2 {
+		t.Errorf("backups: %v, %v", list, err)
+	}
+}
+
+func TestWriteFileExAtomicSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	fn := filepath.Join(dir, "link.txt")
+	os.WriteFile(target, []byte("old"), 0666)
+	if err := os.Symlink("target.txt", fn); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &WriteOptions{Atomic: true, Backup: BackupNameNumeric(".bak", 10)}
+	if _, err := WriteFileEx(fn, []byte("new"), opts); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "new" {
+		t.Errorf("target: got %q", b)
+	}
+	if info, err := os.Lstat(fn); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink was replaced")
+	}
+	backup := filepath.Join(dir, "link.bak.txt")
+	if info, err := os.Lstat(backup); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("backup must be a regular file: %v", err)
+	}
+	if b, _ := os.ReadFile(backup); string(b) != "old" {
+		t.Errorf("backup: got %q", b)
+	}
+}
+
+func TestWriteFileExBackupNameClaimed(t *testing.T) {
+	for _, atomic := range []bool{false, true} {
+		dir := t.TempDir()
+		fn := filepath.Join(dir, "x.txt")
+		os.WriteFile(fn, []byte("old"), 0666)
+
+		// another writer claims the name right after it is generated
+		numeric := BackupNameNumeric(".bak", 10)
+		gen := func(original_fn string, n int) string {
+			backup_fn := numeric(original_fn, n)
+			if n == 1 {
+				os.WriteFile(backup_fn, []byte("other"), 0666)
+			}
+			return backup_fn
+		}
+		if _, err := WriteFileEx(fn, []byte("new"), &WriteOptions{Backup: gen, Atomic: atomic}); err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := os.ReadFile(filepath.Join(dir, "x.bak.txt")); string(b) != "other" {
+			t.Errorf("atomic %v: claimed backup was overwritten with %q", atomic, b)
+		}
+		if b, _ := os.ReadFile(filepath.Join(dir, "x.bak_2.txt")); string(b) != "old" {
+			t.Errorf("atomic %v: backup: %q", atomic, b)
+		}
+	}
+}